@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// azureAppConfigurationApiVersion is the REST API version used by
+// [config.AzureAppConfigurationHttpClient].
+const azureAppConfigurationApiVersion = "1.0"
+
+// NewAzureAppConfigurationHttpClient creates [config.AzureAppConfigurationClient]
+// which talks to the App Configuration REST API at the endpoint, e.g. a local
+// stand-in started with httptest.
+//
+// The client does not authenticate requests. Use WithRequestEditor to add
+// authentication headers when talking to a real App Configuration store.
+func NewAzureAppConfigurationHttpClient(endpoint string) *AzureAppConfigurationHttpClient {
+	return &AzureAppConfigurationHttpClient{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		httpClient: http.DefaultClient,
+	}
+}
+
+// AzureAppConfigurationHttpClient implements [config.AzureAppConfigurationClient] interface.
+type AzureAppConfigurationHttpClient struct {
+	endpoint      string
+	httpClient    *http.Client
+	requestEditor func(r *http.Request) error
+}
+
+// WithHttpClient sets the http.Client used to send requests and returns itself.
+func (c *AzureAppConfigurationHttpClient) WithHttpClient(httpClient *http.Client) *AzureAppConfigurationHttpClient {
+	c.httpClient = httpClient
+	return c
+}
+
+// WithRequestEditor sets the function which is invoked for every request
+// before it is sent, and returns itself.
+func (c *AzureAppConfigurationHttpClient) WithRequestEditor(editor func(r *http.Request) error) *AzureAppConfigurationHttpClient {
+	c.requestEditor = editor
+	return c
+}
+
+// azureAppConfigurationLabelEscaper escapes the characters which have
+// a special meaning in the label filter of the REST API.
+var azureAppConfigurationLabelEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `,`, `\,`)
+
+// ListKeyValues lists key-values, following the "@nextLink" of every page.
+// Part of [config.AzureAppConfigurationClient] interface.
+func (c *AzureAppConfigurationHttpClient) ListKeyValues(keyFilter string, labelFilter string) ([]AzureAppConfigurationSetting, error) {
+	if labelFilter == "" || labelFilter == `\0` {
+		labelFilter = AzureAppConfigurationNullLabel
+	} else {
+		// The label is matched exactly, so the filter syntax is escaped.
+		labelFilter = azureAppConfigurationLabelEscaper.Replace(labelFilter)
+	}
+
+	query := url.Values{}
+	query.Set("key", keyFilter)
+	query.Set("label", labelFilter)
+	query.Set("api-version", azureAppConfigurationApiVersion)
+	next := fmt.Sprintf("%s/kv?%s", c.endpoint, query.Encode())
+
+	var settings []AzureAppConfigurationSetting
+	for next != "" {
+		page, err := c.getPage(next)
+		if err != nil {
+			return nil, err
+		}
+		settings = append(settings, page.Items...)
+
+		next = ""
+		if page.NextLink != "" {
+			u, err := url.Parse(c.endpoint)
+			if err != nil {
+				return nil, err
+			}
+			nextUrl, err := u.Parse(page.NextLink)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid @nextLink '%s'", page.NextLink)
+			}
+			next = nextUrl.String()
+		}
+	}
+
+	return settings, nil
+}
+
+func (c *AzureAppConfigurationHttpClient) getPage(pageUrl string) (*azureAppConfigurationPage, error) {
+	req, err := http.NewRequest(http.MethodGet, pageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.microsoft.appconfig.kvset+json, application/json")
+
+	if c.requestEditor != nil {
+		if err := c.requestEditor(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("GET %s: unexpected status %s", pageUrl, resp.Status)
+	}
+
+	page := &azureAppConfigurationPage{}
+	if err := json.NewDecoder(resp.Body).Decode(page); err != nil {
+		return nil, errors.Wrapf(err, "GET %s", pageUrl)
+	}
+	return page, nil
+}
+
+// azureAppConfigurationPage is a page of key-values in the REST API.
+type azureAppConfigurationPage struct {
+	Items    []AzureAppConfigurationSetting `json:"items"`
+	NextLink string                         `json:"@nextLink"`
+}
+
+// NewAzureAppConfigurationFileClient creates [config.AzureAppConfigurationClient]
+// which reads key-values from a local Json file. The file is either a REST API
+// response ({"items": [...]}) or a plain array of key-values as returned by
+// "az appconfig kv list".
+func NewAzureAppConfigurationFileClient(path string) *AzureAppConfigurationFileClient {
+	return &AzureAppConfigurationFileClient{path: path}
+}
+
+// AzureAppConfigurationFileClient implements [config.AzureAppConfigurationClient] interface.
+type AzureAppConfigurationFileClient struct {
+	path string
+}
+
+// ListKeyValues reads the file and returns matching key-values.
+// Part of [config.AzureAppConfigurationClient] interface.
+func (c *AzureAppConfigurationFileClient) ListKeyValues(keyFilter string, labelFilter string) ([]AzureAppConfigurationSetting, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	all, err := loadAzureAppConfigurationSettings(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", c.path)
+	}
+
+	var settings []AzureAppConfigurationSetting
+	for _, setting := range all {
+		if setting.matches(keyFilter, labelFilter) {
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+func loadAzureAppConfigurationSettings(r io.Reader) ([]AzureAppConfigurationSetting, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '[' {
+		var settings []AzureAppConfigurationSetting
+		err := json.Unmarshal(raw, &settings)
+		return settings, err
+	}
+
+	page := azureAppConfigurationPage{}
+	err := json.Unmarshal(raw, &page)
+	return page.Items, err
+}
@@ -0,0 +1,291 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AzureAppConfigurationNullLabel is the label filter which selects key-values
+// without a label. This is the same as .NET LabelFilter.Null.
+const AzureAppConfigurationNullLabel = "\x00"
+
+// Content types with special meaning in Azure App Configuration.
+const (
+	azureAppConfigurationKeyVaultRefContentType = "application/vnd.microsoft.appconfig.keyvaultref+json"
+	azureAppConfigurationFeatureFlagPrefix      = ".appconfig.featureflag/"
+)
+
+// NewAzureAppConfigurationSource creates configuration source for Azure App Configuration
+// in the same way as .NET AddAzureAppConfiguration does.
+//
+// The key-values are read using the client, which can be backed by a local
+// stand-in of the REST API or by a Json export file, see
+// [config.NewAzureAppConfigurationHttpClient] and [config.NewAzureAppConfigurationFileClient].
+//
+// The selection and precedence rules are the same as in .NET:
+//
+//	- Without any Select, all keys without a label are loaded.
+//	- Later selects take precedence over earlier ones.
+//	- Feature flags are not loaded.
+//	- The first matching prefix given to TrimKeyPrefix is trimmed from keys.
+func NewAzureAppConfigurationSource(client AzureAppConfigurationClient) *AzureAppConfigurationSource {
+	return &AzureAppConfigurationSource{
+		name:   "AzureAppConfigurationSource",
+		client: client,
+	}
+}
+
+// AzureAppConfigurationSource implements [config.Source] interface.
+type AzureAppConfigurationSource struct {
+	name        string
+	client      AzureAppConfigurationClient
+	selectors   []azureAppConfigurationSelector
+	keyPrefixes []string
+	resolver    KeyVaultResolver
+}
+
+type azureAppConfigurationSelector struct {
+	keyFilter   string
+	labelFilter string
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *AzureAppConfigurationSource) WithName(name string) *AzureAppConfigurationSource {
+	s.name = name
+	return s
+}
+
+// Select adds key-values matching key and label filters, same as .NET Select.
+//
+// Key filters are either exact, or end with "*" to match by prefix, e.g.
+// "MyApp:*". Several key filters can be separated by comma. Use "\" to escape
+// "*", "," and "\". The label filter is matched exactly, same as in .NET. The
+// label filter [config.AzureAppConfigurationNullLabel] (or an empty string)
+// selects key-values without a label.
+func (s *AzureAppConfigurationSource) Select(keyFilter string, labelFilter string) *AzureAppConfigurationSource {
+	s.selectors = append(s.selectors, azureAppConfigurationSelector{
+		keyFilter:   keyFilter,
+		labelFilter: labelFilter,
+	})
+	return s
+}
+
+// TrimKeyPrefix trims the prefix from the keys, same as .NET TrimKeyPrefix.
+func (s *AzureAppConfigurationSource) TrimKeyPrefix(prefix string) *AzureAppConfigurationSource {
+	s.keyPrefixes = append(s.keyPrefixes, prefix)
+	return s
+}
+
+// WithKeyVaultResolver sets the resolver for Key Vault references. The entries
+// with resolved references implement [config.KeyVaultEntry].
+//
+// Without the resolver, the Key Vault references are kept as App Service style
+// references, e.g. "@Microsoft.KeyVault(SecretUri=...)", so they can be
+// resolved later with [config.ResolveKeyVaultReferences].
+func (s *AzureAppConfigurationSource) WithKeyVaultResolver(resolver KeyVaultResolver) *AzureAppConfigurationSource {
+	s.resolver = resolver
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *AzureAppConfigurationSource) Name() string {
+	return s.name
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *AzureAppConfigurationSource) Build() (Config, error) {
+	selectors := s.selectors
+	if len(selectors) == 0 {
+		selectors = []azureAppConfigurationSelector{{keyFilter: "*", labelFilter: AzureAppConfigurationNullLabel}}
+	}
+
+	m := make(map[string]string)
+	names := make(foldedKeys)
+	references := make(map[string]KeyVaultReference)
+	for _, selector := range selectors {
+		settings, err := s.client.ListKeyValues(selector.keyFilter, selector.labelFilter)
+		if err != nil {
+			return nil, newSourceError("AzureAppConfigurationSource", s.name, "", err)
+		}
+
+		for _, setting := range settings {
+			if !setting.matches(selector.keyFilter, selector.labelFilter) {
+				continue
+			}
+
+			if strings.HasPrefix(setting.Key, azureAppConfigurationFeatureFlagPrefix) {
+				continue
+			}
+
+			value, ref, err := s.settingValue(setting)
+			if err != nil {
+				return nil, newSourceError("AzureAppConfigurationSource", s.name, "", &ParseError{Key: setting.Key, Err: err})
+			}
+
+			key := s.trimKeyPrefix(setting.Key)
+			names.set(m, key, value)
+			if ref != nil {
+				references[normalizeKey(key)] = *ref
+			} else {
+				delete(references, normalizeKey(key))
+			}
+		}
+	}
+
+	if s.resolver == nil {
+		return newConfigImpl(s, m), nil
+	}
+	return newKeyVaultReferenceConfig(newConfigImpl(s, m), references, s.resolver.Name()), nil
+}
+
+func (s *AzureAppConfigurationSource) trimKeyPrefix(key string) string {
+	for _, prefix := range s.keyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return strings.TrimPrefix(key, prefix)
+		}
+	}
+	return key
+}
+
+// settingValue returns the value of the setting, and the reference when the
+// value was resolved from Key Vault.
+func (s *AzureAppConfigurationSource) settingValue(setting AzureAppConfigurationSetting) (string, *KeyVaultReference, error) {
+	value := ""
+	if setting.Value != nil {
+		value = *setting.Value
+	}
+
+	if !setting.IsKeyVaultReference() {
+		return value, nil, nil
+	}
+
+	var kvRef struct {
+		Uri string `json:"uri"`
+	}
+	if err := json.Unmarshal([]byte(value), &kvRef); err != nil {
+		return "", nil, errors.Wrap(err, "invalid Key Vault reference")
+	}
+
+	if s.resolver == nil {
+		return fmt.Sprintf("%sSecretUri=%s)", keyVaultReferencePrefix, kvRef.Uri), nil, nil
+	}
+
+	ref, err := parseKeyVaultSecretUri(KeyVaultReference{Raw: value}, kvRef.Uri)
+	if err != nil {
+		return "", nil, err
+	}
+
+	value, err = s.resolver.Resolve(ref)
+	if err != nil {
+		return "", nil, err
+	}
+	return value, &ref, nil
+}
+
+// AzureAppConfigurationClient reads key-values from Azure App Configuration.
+type AzureAppConfigurationClient interface {
+	// ListKeyValues returns key-values matching the filters, in the same way as
+	// the "List key-values" operation of the REST API. The filter syntax is
+	// described in [config.AzureAppConfigurationSource.Select].
+	ListKeyValues(keyFilter string, labelFilter string) ([]AzureAppConfigurationSetting, error)
+}
+
+// AzureAppConfigurationSetting is a key-value in the App Configuration REST
+// format. Both REST ("content_type") and Azure CLI ("contentType") property
+// names are accepted when decoding.
+type AzureAppConfigurationSetting struct {
+	Key          string            `json:"key"`
+	Label        *string           `json:"label"`
+	Value        *string           `json:"value"`
+	ContentType  string            `json:"content_type"`
+	ETag         string            `json:"etag"`
+	LastModified string            `json:"last_modified"`
+	Locked       bool              `json:"locked"`
+	Tags         map[string]string `json:"tags"`
+}
+
+// UnmarshalJSON implements [json.Unmarshaler].
+func (s *AzureAppConfigurationSetting) UnmarshalJSON(b []byte) error {
+	type setting AzureAppConfigurationSetting
+	var aux struct {
+		setting
+		ContentTypeCli  string `json:"contentType"`
+		LastModifiedCli string `json:"lastModified"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	*s = AzureAppConfigurationSetting(aux.setting)
+	if s.ContentType == "" {
+		s.ContentType = aux.ContentTypeCli
+	}
+	if s.LastModified == "" {
+		s.LastModified = aux.LastModifiedCli
+	}
+	return nil
+}
+
+// IsKeyVaultReference reports whether the value is a Key Vault reference.
+func (s AzureAppConfigurationSetting) IsKeyVaultReference() bool {
+	mediaType := strings.TrimSpace(strings.SplitN(s.ContentType, ";", 2)[0])
+	return strings.EqualFold(mediaType, azureAppConfigurationKeyVaultRefContentType)
+}
+
+func (s AzureAppConfigurationSetting) matches(keyFilter string, labelFilter string) bool {
+	label := ""
+	if s.Label != nil {
+		label = *s.Label
+	}
+
+	if keyFilter == "" {
+		keyFilter = "*"
+	}
+
+	return matchAzureAppConfigurationKeyFilter(keyFilter, s.Key) &&
+		matchAzureAppConfigurationLabelFilter(labelFilter, label)
+}
+
+// matchAzureAppConfigurationLabelFilter matches the label exactly. Only the
+// null label filter has a special meaning.
+func matchAzureAppConfigurationLabelFilter(filter string, label string) bool {
+	if filter == "" || filter == AzureAppConfigurationNullLabel || filter == `\0` {
+		return label == ""
+	}
+	return filter == label
+}
+
+// matchAzureAppConfigurationKeyFilter matches the value against comma-separated
+// list of exact or prefix ("abc*") filters, with "\" as escape character.
+func matchAzureAppConfigurationKeyFilter(filter string, value string) bool {
+	var text strings.Builder
+	escaped := false
+	for i := 0; i < len(filter); i++ {
+		ch := filter[i]
+		switch {
+		case escaped:
+			text.WriteByte(ch)
+			escaped = false
+		case ch == '\\':
+			escaped = true
+		case ch == ',':
+			if text.String() == value {
+				return true
+			}
+			text.Reset()
+		case ch == '*' && (i == len(filter)-1 || filter[i+1] == ','):
+			if strings.HasPrefix(value, text.String()) {
+				return true
+			}
+			text.Reset()
+			i++
+		default:
+			text.WriteByte(ch)
+		}
+	}
+
+	return text.Len() > 0 && text.String() == value
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+var azureAppConfigurationTestSettings = []AzureAppConfigurationSetting{
+	{Key: "MyApp:Title", Value: strPtr("title no label")},
+	{Key: "MyApp:Title", Label: strPtr("Production"), Value: strPtr("title production")},
+	{Key: "MyApp:Color", Value: strPtr("blue")},
+	{Key: "MyApp:Color", Label: strPtr("Development"), Value: strPtr("red")},
+	{Key: "Other:Setting", Value: strPtr("other")},
+	{Key: "MyApp:Sql", Label: strPtr("Production"), Value: strPtr(`{"uri":"https://v.vault.azure.net/secrets/db"}`),
+		ContentType: "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"},
+	{Key: ".appconfig.featureflag/Beta", Value: strPtr(`{"id":"Beta","enabled":true}`),
+		ContentType: "application/vnd.microsoft.appconfig.ff+json;charset=utf-8"},
+}
+
+// newAzureAppConfigurationTestServer is a local stand-in which returns all
+// settings in two pages regardless of filters.
+func newAzureAppConfigurationTestServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/kv", r.URL.Path)
+		assert.Equal(t, "1.0", r.URL.Query().Get("api-version"))
+
+		page := azureAppConfigurationPage{}
+		if r.URL.Query().Get("after") == "" {
+			page.Items = azureAppConfigurationTestSettings[:3]
+			page.NextLink = "/kv?after=3&api-version=1.0"
+		} else {
+			page.Items = azureAppConfigurationTestSettings[3:]
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func Test_AzureAppConfigurationSource_DefaultSelectsNoLabel(t *testing.T) {
+	server := newAzureAppConfigurationTestServer(t)
+
+	config, err := NewAzureAppConfigurationSource(NewAzureAppConfigurationHttpClient(server.URL)).Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"MyApp:Color", "MyApp:Title", "Other:Setting"}, config.Keys())
+	assert.Equal(t, "title no label", config.Get("MyApp:Title"))
+	assert.Equal(t, "blue", config.Get("MyApp:Color"))
+}
+
+func Test_AzureAppConfigurationSource_LaterSelectsWin(t *testing.T) {
+	server := newAzureAppConfigurationTestServer(t)
+
+	source := NewAzureAppConfigurationSource(NewAzureAppConfigurationHttpClient(server.URL)).
+		Select("MyApp:*", AzureAppConfigurationNullLabel).
+		Select("MyApp:*", "Production").
+		TrimKeyPrefix("MyApp:")
+	config, err := source.Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Color", "Sql", "Title"}, config.Keys())
+	assert.Equal(t, "title production", config.Get("Title"))
+	assert.Equal(t, "blue", config.Get("Color"))
+
+	// Without resolver the reference is kept in App Service syntax.
+	assert.Equal(t, "@Microsoft.KeyVault(SecretUri=https://v.vault.azure.net/secrets/db)", config.Get("Sql"))
+}
+
+func Test_AzureAppConfigurationSource_KeyVaultResolver(t *testing.T) {
+	server := newAzureAppConfigurationTestServer(t)
+	resolver := NewKeyVaultMapResolver(map[string]map[string]string{"v": {"db": "sql secret"}})
+
+	config, err := NewAzureAppConfigurationSource(NewAzureAppConfigurationHttpClient(server.URL)).
+		Select("MyApp:Sql", "Production").
+		WithKeyVaultResolver(resolver).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "sql secret", config.Get("MyApp:Sql"))
+
+	builder := NewBuilder()
+	builder.AddSource(config.Source())
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	entry, ok := root.GetEntry("myapp:sql").(KeyVaultEntry)
+	if assert.True(t, ok) {
+		assert.Equal(t, "sql secret", entry.Value())
+		assert.True(t, entry.Secret())
+		assert.Equal(t, "https://v.vault.azure.net", entry.Vault())
+		assert.Equal(t, "db", entry.Reference().SecretName)
+		assert.Equal(t, resolver.Name(), entry.Resolver())
+		assert.Equal(t, config.Source(), entry.Source())
+	}
+
+	_, err = NewAzureAppConfigurationSource(NewAzureAppConfigurationHttpClient(server.URL)).
+		Select("MyApp:Sql", "Production").
+		WithKeyVaultResolver(NewKeyVaultMapResolver(nil)).
+		Build()
+	assert.Error(t, err)
+}
+
+func Test_AzureAppConfigurationHttpClient_LabelFilter(t *testing.T) {
+	var labels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		labels = append(labels, r.URL.Query().Get("label"))
+		_ = json.NewEncoder(w).Encode(azureAppConfigurationPage{})
+	}))
+	defer server.Close()
+
+	client := NewAzureAppConfigurationHttpClient(server.URL)
+	for _, label := range []string{"", `\0`, "Production", `Dev,Prod*\`} {
+		_, err := client.ListKeyValues("*", label)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"\x00", "\x00", "Production", `Dev\,Prod\*\\`}, labels)
+}
+
+func Test_AzureAppConfigurationSource_HttpError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewAzureAppConfigurationSource(NewAzureAppConfigurationHttpClient(server.URL)).Build()
+	assert.Error(t, err)
+}
+
+func Test_AzureAppConfigurationFileClient(t *testing.T) {
+	// Format of "az appconfig kv list".
+	export := `[
+		{"key": "MyApp:Title", "label": null, "value": "title", "contentType": null},
+		{"key": "MyApp:Title", "label": "Production", "value": "title production", "contentType": ""},
+		{"key": "MyApp:Sql", "label": "Production", "value": "{\"uri\":\"https://v.vault.azure.net/secrets/db\"}",
+		 "contentType": "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"}
+	]`
+	path := filepath.Join(t.TempDir(), "export.json")
+	assert.NoError(t, os.WriteFile(path, []byte(export), 0600))
+
+	config, err := NewAzureAppConfigurationSource(NewAzureAppConfigurationFileClient(path)).
+		Select("*", `\0`).
+		Select("MyApp:Title,MyApp:Sql", "Production").
+		Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "title production", config.Get("MyApp:Title"))
+	assert.Equal(t, "@Microsoft.KeyVault(SecretUri=https://v.vault.azure.net/secrets/db)", config.Get("MyApp:Sql"))
+}
+
+func Test_matchAzureAppConfigurationKeyFilter(t *testing.T) {
+	assert.True(t, matchAzureAppConfigurationKeyFilter("*", "anything"))
+	assert.True(t, matchAzureAppConfigurationKeyFilter("abc", "abc"))
+	assert.False(t, matchAzureAppConfigurationKeyFilter("abc", "abcd"))
+	assert.True(t, matchAzureAppConfigurationKeyFilter("abc*", "abcd"))
+	assert.True(t, matchAzureAppConfigurationKeyFilter("xyz,abc*", "abcd"))
+	assert.True(t, matchAzureAppConfigurationKeyFilter("abc*,xyz", "xyz"))
+	assert.True(t, matchAzureAppConfigurationKeyFilter(`a\*b`, "a*b"))
+	assert.True(t, matchAzureAppConfigurationKeyFilter(`a\,b`, "a,b"))
+	assert.False(t, matchAzureAppConfigurationKeyFilter(`a\*`, "ab"))
+
+	assert.True(t, matchAzureAppConfigurationLabelFilter(AzureAppConfigurationNullLabel, ""))
+	assert.False(t, matchAzureAppConfigurationLabelFilter(AzureAppConfigurationNullLabel, "Production"))
+	assert.True(t, matchAzureAppConfigurationLabelFilter("Production", "Production"))
+	assert.False(t, matchAzureAppConfigurationLabelFilter("*", "Production"))
+	assert.True(t, matchAzureAppConfigurationLabelFilter("*", "*"))
+	assert.False(t, matchAzureAppConfigurationLabelFilter("Dev,Prod", "Prod"))
+	assert.True(t, matchAzureAppConfigurationLabelFilter("Dev,Prod", "Dev,Prod"))
+	assert.False(t, matchAzureAppConfigurationLabelFilter(`Prod\*`, "Prod*"))
+}
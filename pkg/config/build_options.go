@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BuildOptions are the options of [config.Builder.BuildWithOptions].
+type BuildOptions struct {
+	// ContinueOnError builds the configuration from the sources which can be
+	// built, skipping the failing sources, e.g. so inspection tools can report
+	// all broken files at once. The returned RootConfig is usable even when
+	// the error, [config.BuildErrors], is not nil. Reload does not fail
+	// either, the failing sources are reported by [config.RootConfig.Diagnostics].
+	ContinueOnError bool
+	// LegacyKeyNormalization converts double underscore "__" to the key
+	// delimiter ":" in the keys of all sources, see [config.NewLegacyKeySource],
+	// and in the keys looked up in the returned RootConfig, as this package did
+	// before. This is decided once when the configuration is built.
+	LegacyKeyNormalization bool
+}
+
+// BuildDiagnostic describes a source which provides no values.
+type BuildDiagnostic struct {
+	// Source is the source.
+	Source Source
+	// Optional reports whether the source is optional and does not exist,
+	// see [config.NewOptionalSource]. Otherwise the source failed to build.
+	Optional bool
+	// Err is the error of the source, usually [config.SourceError].
+	Err error
+}
+
+// BuildErrors is a list of all sources which failed to build, see
+// [config.BuildOptions].
+type BuildErrors []BuildDiagnostic
+
+func (e BuildErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, diagnostic := range e {
+		messages = append(messages, diagnostic.Err.Error())
+	}
+	return fmt.Sprintf("%d sources failed to build: %s", len(e), strings.Join(messages, "; "))
+}
+
+func (c *rootConfigImpl) Diagnostics() []BuildDiagnostic {
+	var diagnostics []BuildDiagnostic
+	diagnostics = append(diagnostics, c.getBuildErrors()...)
+	diagnostics = append(diagnostics, c.getSnapshot().optional...)
+	return diagnostics
+}
+
+func (c *rootConfigImpl) getBuildErrors() BuildErrors {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.buildErrors
+}
+
+func (c *rootConfigImpl) setBuildErrors(buildErrors BuildErrors) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buildErrors = buildErrors
+}
+
+// NewOptionalSource creates a source which wraps a source which may not exist,
+// like ASP.NET optional files. When the source fails because it is not found,
+// i.e. the error is fs.ErrNotExist, it provides no values instead of the error,
+// and the error is reported by [config.RootConfig.Diagnostics]. Other errors
+// are returned as-is.
+func NewOptionalSource(source Source) *OptionalSource {
+	return &OptionalSource{
+		source: source,
+	}
+}
+
+// OptionalSource implements [config.Source] interface.
+type OptionalSource struct {
+	source Source
+}
+
+// Source returns the wrapped source.
+func (s *OptionalSource) Source() Source {
+	return s.source
+}
+
+// Name is the name of the wrapped source. Part of [config.Source] interface.
+func (s *OptionalSource) Name() string {
+	return s.source.Name()
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *OptionalSource) Build() (Config, error) {
+	return s.BuildWithDefaults(nil)
+}
+
+// BuildWithDefaults passes the builder properties to the wrapped source.
+// Part of [config.DefaultsSource] interface.
+func (s *OptionalSource) BuildWithDefaults(properties map[string]interface{}) (Config, error) {
+	config, err := buildWithDefaults(s.source, properties)
+	if err != nil && errors.Is(err, fs.ErrNotExist) {
+		return &missingConfig{configImpl: newConfigImpl(s, nil), err: err}, nil
+	}
+	return config, err
+}
+
+// missingConfig is the empty config of [config.OptionalSource] which does
+// not exist.
+type missingConfig struct {
+	*configImpl
+	err error
+}
+
+// optionalSourceError returns the error of the optional source of the
+// provider which does not exist, or nil.
+func optionalSourceError(provider Provider) error {
+	p, ok := provider.(*configProvider)
+	if !ok {
+		return nil
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if missing, ok := p.config.(*missingConfig); ok {
+		return missing.err
+	}
+	return nil
+}
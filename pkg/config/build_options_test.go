@@ -0,0 +1,86 @@
+package config
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Builder_ContinueOnError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"appsettings.json":     {Data: []byte(`{"a": "json"}`)},
+		"broken.json":          {Data: []byte(`{"a": `)},
+		"also-broken.json":     {Data: []byte(`{"b": 1, "B": 2}`)},
+		"appsettings.Dev.json": {Data: []byte(`{"b": "dev"}`)},
+	}
+
+	builder := NewBuilder()
+	builder.Properties()[PropertyFileSystem] = fsys
+	builder.AddSource(NewFileSource("appsettings.json"))
+	builder.AddSource(NewFileSource("broken.json"))
+	builder.AddSource(NewFileSource("also-broken.json"))
+	builder.AddSource(NewFileSource("appsettings.Dev.json"))
+
+	_, err := builder.Build()
+	assert.Error(t, err)
+
+	root, err := builder.BuildWithOptions(BuildOptions{ContinueOnError: true})
+	assert.Equal(t, "json", root.Get("a"))
+	assert.Equal(t, "dev", root.Get("b"))
+	assert.Len(t, root.Providers(), 2)
+
+	var buildErrs BuildErrors
+	if assert.True(t, errors.As(err, &buildErrs)) && assert.Len(t, buildErrs, 2) {
+		assert.Equal(t, "broken.json", buildErrs[0].Source.Name())
+		assert.Equal(t, "also-broken.json", buildErrs[1].Source.Name())
+		assert.False(t, buildErrs[1].Optional)
+
+		var duplicateErr *DuplicateKeyError
+		assert.True(t, errors.As(buildErrs[1].Err, &duplicateErr))
+	}
+	assert.Contains(t, err.Error(), "2 sources failed to build: FileSource: broken.json: ")
+	assert.Equal(t, []BuildDiagnostic(buildErrs), root.Diagnostics())
+
+	// Reload does not fail, and updates the diagnostics.
+	fsys["broken.json"] = &fstest.MapFile{Data: []byte(`{"a": "fixed"}`)}
+	assert.NoError(t, root.Reload())
+	assert.Equal(t, "fixed", root.Get("a"))
+	assert.Len(t, root.Diagnostics(), 1)
+}
+
+func Test_OptionalSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"appsettings.json":        {Data: []byte(`{"a": "json"}`)},
+		"appsettings.broken.json": {Data: []byte(`{`)},
+	}
+
+	builder := NewBuilder()
+	builder.Properties()[PropertyFileSystem] = fsys
+	builder.AddSource(NewFileSource("appsettings.json"))
+	builder.AddSource(NewOptionalSource(NewFileSource("appsettings.Production.json")))
+
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "json", root.Get("a"))
+
+	diagnostics := root.Diagnostics()
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, "appsettings.Production.json", diagnostics[0].Source.Name())
+		assert.True(t, diagnostics[0].Optional)
+		assert.True(t, errors.Is(diagnostics[0].Err, fs.ErrNotExist))
+	}
+
+	// The file is picked up on reload once it exists.
+	fsys["appsettings.Production.json"] = &fstest.MapFile{Data: []byte(`{"a": "production"}`)}
+	assert.NoError(t, root.Reload())
+	assert.Equal(t, "production", root.Get("a"))
+	assert.Empty(t, root.Diagnostics())
+
+	// Only not found errors are ignored.
+	builder.AddSource(NewOptionalSource(NewFileSource("appsettings.broken.json")))
+	_, err = builder.Build()
+	assert.Error(t, err)
+}
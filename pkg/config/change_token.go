@@ -0,0 +1,112 @@
+package config
+
+import (
+	"sync"
+)
+
+// ChangeToken propagates notifications that a change has occurred. This is
+// similar to ASP.NET IChangeToken interface.
+//
+// A token changes only once. To get notified about further changes, get a new
+// token from its owner, e.g. [config.Provider.GetReloadToken].
+type ChangeToken interface {
+	// HasChanged reports whether a change has occurred.
+	HasChanged() bool
+	// RegisterChangeCallback registers the callback which is invoked when the
+	// change occurs, or right away if the change has already occurred. The
+	// returned function unregisters the callback.
+	RegisterChangeCallback(callback func()) (unregister func())
+}
+
+// newChangeToken creates new instance of [config.ChangeToken] which is
+// changed with onChange.
+func newChangeToken() *changeTokenImpl {
+	return &changeTokenImpl{
+		callbacks: make(map[int]func()),
+	}
+}
+
+// changeTokenImpl implements [config.ChangeToken] interface.
+type changeTokenImpl struct {
+	mu        sync.Mutex
+	changed   bool
+	callbacks map[int]func()
+	nextId    int
+}
+
+func (t *changeTokenImpl) HasChanged() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+func (t *changeTokenImpl) RegisterChangeCallback(callback func()) (unregister func()) {
+	t.mu.Lock()
+	if t.changed {
+		t.mu.Unlock()
+		callback()
+		return func() {}
+	}
+
+	id := t.nextId
+	t.nextId++
+	t.callbacks[id] = callback
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.callbacks, id)
+	}
+}
+
+// onChange marks the token as changed and invokes the callbacks in the order
+// they were registered. Only the first call has any effect.
+func (t *changeTokenImpl) onChange() {
+	t.mu.Lock()
+	if t.changed {
+		t.mu.Unlock()
+		return
+	}
+	t.changed = true
+	callbacks := make([]func(), 0, len(t.callbacks))
+	for id := 0; id < t.nextId; id++ {
+		if callback, found := t.callbacks[id]; found {
+			callbacks = append(callbacks, callback)
+		}
+	}
+	t.callbacks = nil
+	t.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// reloadToken hands out change tokens which are changed and replaced on each
+// reload, in the same way as ASP.NET ConfigurationReloadToken is used.
+type reloadToken struct {
+	mu    sync.Mutex
+	token *changeTokenImpl
+}
+
+func newReloadToken() *reloadToken {
+	return &reloadToken{token: newChangeToken()}
+}
+
+// get returns the token for the next reload.
+func (r *reloadToken) get() ChangeToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.token
+}
+
+// onReload replaces the token and changes the old one.
+func (r *reloadToken) onReload() {
+	r.mu.Lock()
+	previous := r.token
+	r.token = newChangeToken()
+	r.mu.Unlock()
+
+	previous.onChange()
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ChangeToken(t *testing.T) {
+	token := newChangeToken()
+
+	var calls []string
+	token.RegisterChangeCallback(func() { calls = append(calls, "first") })
+	unregister := token.RegisterChangeCallback(func() { calls = append(calls, "removed") })
+	token.RegisterChangeCallback(func() { calls = append(calls, "second") })
+	unregister()
+
+	assert.False(t, token.HasChanged())
+	token.onChange()
+	token.onChange()
+	assert.True(t, token.HasChanged())
+	assert.Equal(t, []string{"first", "second"}, calls)
+
+	// Callbacks registered after the change are invoked right away.
+	token.RegisterChangeCallback(func() { calls = append(calls, "late") })
+	assert.Equal(t, []string{"first", "second", "late"}, calls)
+}
+
+func Test_ReloadToken(t *testing.T) {
+	r := newReloadToken()
+	first := r.get()
+
+	r.onReload()
+	second := r.get()
+	assert.True(t, first.HasChanged())
+	assert.False(t, second.HasChanged())
+	assert.NotSame(t, first, second)
+}
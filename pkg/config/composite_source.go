@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NewCompositeSource creates [config.Source] which combines the sources in
+// precedence order, i.e. the later sources take precedence over earlier ones.
+// This is the Source of [config.RootConfig], and describes its full stack.
+func NewCompositeSource(sources ...Source) *CompositeSource {
+	return &CompositeSource{sources: sources}
+}
+
+// CompositeSource implements [config.Source] interface.
+type CompositeSource struct {
+	sources []Source
+}
+
+// Name lists the names of the sources, e.g. "CompositeSource: appsettings.json, EnvVarsSource".
+// Part of [config.Source] interface.
+func (s *CompositeSource) Name() string {
+	names := make([]string, 0, len(s.sources))
+	for _, source := range s.sources {
+		names = append(names, source.Name())
+	}
+	return fmt.Sprintf("CompositeSource: %s", strings.Join(names, ", "))
+}
+
+// Sources returns the sources in precedence order.
+func (s *CompositeSource) Sources() []Source {
+	sources := make([]Source, len(s.sources))
+	copy(sources, s.sources)
+	return sources
+}
+
+// Build builds [config.RootConfig] from the sources. Part of [config.Source] interface.
+func (s *CompositeSource) Build() (Config, error) {
+	builder := NewBuilder()
+	for _, source := range s.sources {
+		builder.AddSource(source)
+	}
+	return builder.Build()
+}
@@ -2,16 +2,29 @@
 //
 // Main features:
 //
-//	- Functionality and behaviour should be identical to [ASP.NET].
+//	- Functionality and behaviour should be identical to [ASP.NET] 7.0. This
+//	  is the same as 6.0, except that empty Json arrays provide a key with an
+//	  empty value, like empty Json objects.
 //	- Support various configuration sources like Json and Environmental Variables.
 //	- Support for hierarchical keys.
-//	- Case-insensitive key names.
+//	- Case-insensitive key names, which keep their original casing like in [ASP.NET].
 //	- Hopefully simple and intuitive usage.
 //
 // Additional features:
 //
 //	- Ability to inspect configuration and determine the source of values.
 //	  This is to support various DevOps and troubleshooting tooling.
+//	- Resolution of App Service Key Vault references, see [ResolveKeyVaultReferences].
+//	- Reload with notifications of changed keys, see [RootConfig.Reload] and [RootConfig.OnChange].
+//	- Reload when files change, including Kubernetes ConfigMap updates, see [ReloadOnChange].
+//	- Sources added to a live configuration are visible right away, see [Manager].
+//	- Rendering and parsing of ASP.NET debug view, see [GetDebugView] and [ParseDebugView].
+//	- Location of values inside their sources, e.g. Json file line and column, see [LocatedEntry].
+//	- Keys spelled with different casing by different sources, see [FindKeyCasingConflicts].
+//	- Kinds of Json values, e.g. to tell null from empty string, see [JsonEntry].
+//	- Typed errors with the file, line and column, see [SourceError] and [ParseError].
+//	- Optional sources and builds which report all failing sources at once, see [BuildOptions].
+//	- Lock-free reads, and frozen point-in-time views for consistent reads, see [RootConfig.Snapshot].
 //
 // Motivation:
 //
@@ -22,34 +35,79 @@
 //
 // Current configuration providers:
 //
-//	- Json from user-supplied [[]byte] array. File support coming soon.
+//	- Json from user-supplied [[]byte] array, or from a file in [fs.FS].
+//	- Files and streams in any registered format, picked by file extension, see [RegisterFormat].
+//	- Files as they exist at a git revision, see [GitFS].
+//	- Files and Env of a container image tarball, see [LoadContainerImage].
 //	- Environmental Variables.
 //	- Environmental Variables from user-supplied [map[string]string].
+//	- Environmental Variables from listings like printenv or PowerShell output, see [ParseEnvDump].
+//	- Azure App Configuration, from a local stand-in of REST API or an export file.
+//	- AWS Systems Manager Parameter Store, from a local fixture of GetParametersByPath output.
+//	- Json documents published over HTTP, with ETag polling and offline cache.
+//	- Json encrypted with SOPS, decrypted in memory. Decrypting the data key
+//	  with an age identity runs the "age" command, see [SopsJsonSource].
+//	- Azure Functions local.settings.json.
 //
 // Limitations and unimplemented features:
 //
-//	- Currently read-only versions of everything.
-//	- No support for refresh notifications.
+//	- Currently read-only versions of everything, except values set with [Manager.Set].
 //	- No support for many sources like INI files but these may be added later.
 //
 // See examples for basic and more advanced usage.
 //
-// [ASP.NET]: https://github.com/dotnet/runtime/tree/release/6.0/src/libraries/Microsoft.Extensions.Configuration/src
+// [ASP.NET]: https://github.com/dotnet/runtime/tree/release/7.0/src/libraries/Microsoft.Extensions.Configuration/src
 package config
 
 import (
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
 )
 
 // keyDelimiter is a hierarchical delimiter for keys.
 const keyDelimiter = ":"
 
-// normalizeKey is applied to all keys when adding and querying values.
+// normalizeKey is applied to all keys when adding and querying values. The
+// keys are compared case-insensitively like with ASP.NET ConfigurationKeyComparer.
 func normalizeKey(key string) string {
-	key = strings.ToLower(key)
-	key = strings.Replace(key, "__", keyDelimiter, -1)
-	return key
+	return strings.ToLower(key)
+}
+
+// envKeyName converts double underscore "__" in the name of environment
+// variable to [config.keyDelimiter], like ASP.NET does for environment
+// variables and key-per-file.
+func envKeyName(name string) string {
+	return strings.Replace(name, "__", keyDelimiter, -1)
+}
+
+// sortKeys sorts the keys alphabetically ignoring the casing.
+func sortKeys(keys []string) {
+	sort.Slice(keys, func(i int, j int) bool {
+		left, right := normalizeKey(keys[i]), normalizeKey(keys[j])
+		if left != right {
+			return left < right
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+// foldedKeys keeps the casing of the keys of a map with the values, so a
+// later value of the key with different casing replaces the value but not the
+// casing, same as .NET dictionaries with case-insensitive comparer.
+type foldedKeys map[string]string
+
+// set sets the value of the key in the map.
+func (f foldedKeys) set(m map[string]string, key string, value string) {
+	normalized := normalizeKey(key)
+	if first, found := f[normalized]; found {
+		key = first
+	}
+	f[normalized] = key
+	m[key] = value
 }
 
 // Source is a common interface for types which can provide Config, e.g.
@@ -71,7 +129,12 @@ type Source interface {
 //
 // Key normalisation happens to provide case-insensitive experience like in ASP.NET:
 //   - Converted to lower case.
-//   - Double underscore  "__" is converted to [config.keyDelimiter].
+//   - Double underscore  "__" is converted to [config.keyDelimiter] only by the
+//     sources which do this in ASP.NET, e.g. environment variables, or by the
+//     sources wrapped with [config.NewLegacyKeySource].
+//
+// Like in ASP.NET, the keys listed by Keys keep the casing of the source, e.g.
+// "ConnectionStrings:Sql".
 type Config interface {
 	// Get returns a value for the specified key. The keys are normalised and
 	// are not case-sensitive. See [config.normalizeKey] function.
@@ -79,7 +142,8 @@ type Config interface {
 	// TryGet returns a value for the specified key and an indicator whether it exists.
 	// The keys are normalised and are not case-sensitive. See [config.normalizeKey] function.
 	TryGet(key string, val *string) (found bool)
-	// Keys lists all keys in configuration. The list is in alphabetical order.
+	// Keys lists all keys in configuration with their original casing. The list
+	// is in alphabetical order ignoring the casing.
 	// Note: this method is not part of .NET IConfiguration.
 	Keys() []string
 	// Source returns the [config.Source] which provided this Config.
@@ -89,20 +153,24 @@ type Config interface {
 // newConfigImpl creates an instance of Config interface.
 func newConfigImpl(configSource Source, m map[string]string) *configImpl {
 	m2 := make(map[string]string, len(m))
+	names := make(map[string]string, len(m))
 	for k, v := range m {
-		k = normalizeKey(k)
-		m2[k] = v
+		normalized := normalizeKey(k)
+		m2[normalized] = v
+		names[normalized] = k
 	}
 
 	return &configImpl{
 		configSource: configSource,
 		m:            m2,
+		names:        names,
 	}
 }
 
 // configImpl implements Config interface.
 type configImpl struct {
 	m            map[string]string
+	names        map[string]string
 	configSource Source
 }
 
@@ -118,11 +186,11 @@ func (c *configImpl) TryGet(key string, val *string) (found bool) {
 
 func (c *configImpl) Keys() []string {
 	var keys []string
-	for k := range c.m {
-		keys = append(keys, k)
+	for _, name := range c.names {
+		keys = append(keys, name)
 	}
 
-	sort.Strings(keys)
+	sortKeys(keys)
 	return keys
 }
 
@@ -137,39 +205,179 @@ type Builder interface {
 	// end of the existing list. The source further down the list take precedence
 	// over sources which are earlier in the list.
 	AddSource(source Source)
+	// InsertSource inserts the source at the index in the list of sources.
+	// The index must be between 0 and the number of sources.
+	InsertSource(index int, source Source) error
+	// RemoveSource removes all sources with the name, and reports whether any
+	// source was removed.
+	RemoveSource(name string) bool
+	// Sources returns the sources in precedence order, i.e. the later sources
+	// take precedence over earlier ones.
+	Sources() []Source
+	// Properties returns the properties shared between sources, like ASP.NET
+	// IConfigurationBuilder.Properties, e.g. [config.PropertyBasePath]. The
+	// sources implementing [config.DefaultsSource] take defaults from these.
+	// The returned map is not a copy.
+	Properties() map[string]interface{}
 	// Build builds the [config.RootConfig] object from the current list of sources.
 	// Once built, any changes to the sources have no effect. If there are changes
 	// in the sources, invoke Build again.
 	Build() (RootConfig, error)
+	// BuildWithOptions is same as Build, with the options, e.g. to build the
+	// configuration from the sources which can be built when others fail.
+	BuildWithOptions(options BuildOptions) (RootConfig, error)
+}
+
+// Well-known [config.Builder] properties.
+const (
+	// PropertyBasePath is the directory, string, which relative paths of file
+	// sources are relative to. Same as ASP.NET SetBasePath.
+	PropertyBasePath = "BasePath"
+	// PropertyFileSystem is the [fs.FS] which file sources read files from,
+	// when not given explicitly. Same as ASP.NET SetFileProvider.
+	PropertyFileSystem = "FileProvider"
+)
+
+// DefaultsSource is implemented by sources which take defaults from the
+// builder properties, like ASP.NET FileConfigurationSource.EnsureDefaults.
+type DefaultsSource interface {
+	Source
+	// BuildWithDefaults is invoked by the builder instead of Build, and takes
+	// the defaults which are not set explicitly from the properties. The
+	// source itself is not changed, so it can be shared between builders.
+	BuildWithDefaults(properties map[string]interface{}) (Config, error)
+}
+
+// buildWithDefaults builds the source with the builder properties if it is
+// [config.DefaultsSource], e.g. when it is wrapped by another source.
+func buildWithDefaults(source Source, properties map[string]interface{}) (Config, error) {
+	if ds, ok := source.(DefaultsSource); ok {
+		return ds.BuildWithDefaults(properties)
+	}
+	return source.Build()
 }
 
 // NewBuilder create new instance of [config.Builder] implementation.
 func NewBuilder() Builder {
-	return &builderImpl{}
+	return &builderImpl{
+		properties: make(map[string]interface{}),
+	}
 }
 
 // builderImpl implements [config.Builder] interface.
 type builderImpl struct {
-	sources []Source
+	sources    []Source
+	properties map[string]interface{}
 }
 
 func (b *builderImpl) AddSource(source Source) {
 	b.sources = append(b.sources, source)
 }
 
-func (b *builderImpl) Build() (RootConfig, error) {
-	root := newRootConfigImpl()
+func (b *builderImpl) InsertSource(index int, source Source) error {
+	if index < 0 || index > len(b.sources) {
+		return errors.Errorf("Builder: index %d is out of range [0, %d]", index, len(b.sources))
+	}
+
+	b.sources = append(b.sources, nil)
+	copy(b.sources[index+1:], b.sources[index:])
+	b.sources[index] = source
+	return nil
+}
+
+func (b *builderImpl) RemoveSource(name string) bool {
+	var sources []Source
 	for _, source := range b.sources {
-		config, err := source.Build()
-		if err != nil {
-			return nil, err
+		if source.Name() != name {
+			sources = append(sources, source)
+		}
+	}
+
+	removed := len(sources) != len(b.sources)
+	b.sources = sources
+	return removed
+}
+
+func (b *builderImpl) Sources() []Source {
+	sources := make([]Source, len(b.sources))
+	copy(sources, b.sources)
+	return sources
+}
+
+func (b *builderImpl) Properties() map[string]interface{} {
+	return b.properties
+}
+
+func (b *builderImpl) Build() (RootConfig, error) {
+	return b.BuildWithOptions(BuildOptions{})
+}
+
+func (b *builderImpl) BuildWithOptions(options BuildOptions) (RootConfig, error) {
+	sources := b.Sources()
+	properties := make(map[string]interface{}, len(b.properties))
+	for name, value := range b.properties {
+		properties[name] = value
+	}
+	if options.LegacyKeyNormalization {
+		for i, source := range sources {
+			sources[i] = NewLegacyKeySource(source)
 		}
-		root.addConfig(config)
 	}
 
+	var root *rootConfigImpl
+	reloader := func() ([]Provider, error) {
+		providers, err := loadProviders(sources, properties, options.ContinueOnError)
+		if options.ContinueOnError {
+			// The failing sources are reported by Diagnostics.
+			buildErrs, _ := err.(BuildErrors)
+			root.setBuildErrors(buildErrs)
+			return providers, nil
+		}
+		return providers, err
+	}
+	root = newRootConfigImpl(reloader)
+	root.legacyKeys = options.LegacyKeyNormalization
+
+	providers, err := reloader()
+	if err != nil {
+		return nil, err
+	}
+
+	root.setProviders(providers)
+	if buildErrs := root.getBuildErrors(); len(buildErrs) > 0 {
+		return root, buildErrs
+	}
 	return root, nil
 }
 
+// loadProviders builds and loads providers for the sources, with the builder
+// properties, which may be nil. With continueOnError, the providers of the failing sources are skipped, and the
+// error is [config.BuildErrors] listing all of them.
+func loadProviders(sources []Source, properties map[string]interface{}, continueOnError bool) ([]Provider, error) {
+	providers := make([]Provider, 0, len(sources))
+	var buildErrs BuildErrors
+	for _, source := range sources {
+		provider, err := buildProvider(source, properties)
+		if err == nil {
+			err = provider.Load()
+		}
+
+		if err != nil {
+			if !continueOnError {
+				return nil, err
+			}
+			buildErrs = append(buildErrs, BuildDiagnostic{Source: source, Err: err})
+			continue
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(buildErrs) > 0 {
+		return providers, buildErrs
+	}
+	return providers, nil
+}
+
 // RootConfig is the main top-level configuration object which aggregates
 // configuration from multiple configuration sources and provides and "effective
 // configuration".
@@ -190,21 +398,84 @@ type RootConfig interface {
 	// source of the value.
 	GetEntry(key string) Entry
 	// GetEntries returns a list of [config.Entry]. The list is sorted by keys.
+	// The keys of the entries have the casing of the first provider which
+	// has the key, like ASP.NET does, see [config.FindKeyCasingConflicts].
 	GetEntries() []Entry
+	// GetEntryChain returns the values of the key in every layer which sets it,
+	// including the values overridden by later layers.
+	GetEntryChain(key string) EntryChain
+	// GetEntriesWithHistory returns [config.EntryChain] of every key. The list
+	// is sorted by keys.
+	GetEntriesWithHistory() []EntryChain
+	// GetChildKeys returns the distinct immediate child keys of the parent path
+	// across all providers, in the same order as ASP.NET GetChildren. An empty
+	// parent path means the top level keys.
+	GetChildKeys(parentPath string) []string
+	// Providers returns the providers of this configuration in precedence
+	// order, i.e. the later providers take precedence over earlier ones.
+	Providers() []Provider
+	// Diagnostics returns the sources which provide no values, because they
+	// failed to build, see [config.BuildOptions], or they are optional and do
+	// not exist, see [config.NewOptionalSource].
+	Diagnostics() []BuildDiagnostic
+	// Reload builds and loads all sources again, and replaces all providers
+	// at once, so readers never see a mix of old and new values. On error the
+	// configuration is not changed.
+	Reload() error
+	// Snapshot returns a copy of the configuration at this point in time,
+	// which does not change when the configuration or its providers reload,
+	// e.g. to read related values consistently while handling a request.
+	Snapshot() RootConfig
+	// GetReloadToken returns a [config.ChangeToken] which changes when this
+	// configuration is reloaded, or any of its providers reloads.
+	GetReloadToken() ChangeToken
+	// OnChange registers the callback which is invoked with the changed keys
+	// when reload changes any values. The returned function unregisters the callback.
+	OnChange(callback func(changes []ConfigChange)) (unregister func())
 }
 
-// newRootConfigImpl creates new instance of [config.RootConfig].
-func newRootConfigImpl() *rootConfigImpl {
-	return &rootConfigImpl{}
+// newRootConfigImpl creates new instance of [config.RootConfig]. The reloader
+// builds new providers on Reload.
+func newRootConfigImpl(reloader func() ([]Provider, error)) *rootConfigImpl {
+	c := &rootConfigImpl{
+		reloader:    reloader,
+		reloadToken: newReloadToken(),
+		callbacks:   make(map[int]func(changes []ConfigChange)),
+	}
+	c.snapshot.Store(&rootSnapshot{})
+	return c
 }
 
 // rootConfigImpl implements [config.RootConfig]
 type rootConfigImpl struct {
-	configs []Config
+	reloader    func() ([]Provider, error)
+	reloadToken *reloadToken
+	reloadMu    sync.Mutex
+	// legacyKeys converts "__" in the keys which are looked up, see
+	// [config.BuildOptions].
+	legacyKeys bool
+	// derive, when set, replaces the providers when they are refreshed, e.g.
+	// to resolve Key Vault references again, see [config.ResolveKeyVaultReferences].
+	derive func(providers []Provider) []Provider
+
+	// snapshot is the current *rootSnapshot. Readers load it without locks,
+	// and writers replace it at once while holding reloadMu.
+	snapshot atomic.Value
+
+	mu          sync.RWMutex
+	watchers    []*providerWatcher
+	reloading   bool
+	buildErrors BuildErrors
+
+	callbacksMu sync.Mutex
+	callbacks   map[int]func(changes []ConfigChange)
+	nextId      int
 }
 
-func (c *rootConfigImpl) addConfig(config Config) {
-	c.configs = append(c.configs, config)
+// getProviders returns the current providers. Readers use the same providers
+// for the whole operation, so the result is consistent during Reload.
+func (c *rootConfigImpl) getProviders() []Provider {
+	return c.getSnapshot().providers
 }
 
 func (c *rootConfigImpl) Get(key string) string {
@@ -218,25 +489,21 @@ func (c *rootConfigImpl) TryGet(key string, val *string) (found bool) {
 }
 
 func (c *rootConfigImpl) Keys() []string {
-	keysSet := make(map[string]interface{})
-	for _, config := range c.configs {
-		for _, key := range config.Keys() {
-			keysSet[key] = nil
-		}
-	}
-
-	var keys []string
-	for key := range keysSet {
-		keys = append(keys, key)
-	}
-
-	sort.Strings(keys)
+	current := c.getSnapshot().keys
+	keys := make([]string, len(current))
+	copy(keys, current)
 	return keys
 }
 
+// Source returns [config.CompositeSource] with the sources of all providers.
 func (c *rootConfigImpl) Source() Source {
-	// TODO(ppanyukov): implement proper source for RootConfig
-	return nil
+	var sources []Source
+	for _, provider := range c.getProviders() {
+		if source := provider.Source(); source != nil {
+			sources = append(sources, source)
+		}
+	}
+	return NewCompositeSource(sources...)
 }
 
 func (c *rootConfigImpl) GetEntry(key string) Entry {
@@ -245,39 +512,63 @@ func (c *rootConfigImpl) GetEntry(key string) Entry {
 }
 
 func (c *rootConfigImpl) GetEntries() []Entry {
-	entrySet := make(map[string]Entry)
-	for _, config := range c.configs {
-		for _, key := range config.Keys() {
-			val := config.Get(key)
-			entrySet[key] = newEntryImpl(key, val, config.Source())
+	snapshot := c.getSnapshot()
+	entries := make([]Entry, 0, len(snapshot.keys))
+	for _, key := range snapshot.keys {
+		if entry, found := snapshot.entries[normalizeKey(key)]; found {
+			entries = append(entries, entry)
 		}
 	}
+	return entries
+}
 
-	var entries []Entry
-	for _, v := range entrySet {
-		entries = append(entries, v)
-	}
+func (c *rootConfigImpl) GetChildKeys(parentPath string) []string {
+	return c.getSnapshot().getChildKeys(c.queryKey(parentPath))
+}
 
-	sort.Slice(entries, func(i int, j int) bool {
-		left := entries[i]
-		right := entries[j]
-		return strings.Compare(left.Key(), right.Key()) == -1
-	})
+func (c *rootConfigImpl) Providers() []Provider {
+	current := c.getProviders()
+	providers := make([]Provider, len(current))
+	copy(providers, current)
+	return providers
+}
 
-	return entries
+// queryKey returns the key which is looked up for the key.
+func (c *rootConfigImpl) queryKey(key string) string {
+	if c.legacyKeys {
+		return envKeyName(key)
+	}
+	return key
 }
 
 func (c *rootConfigImpl) tryGetEntry(key string) (result Entry, found bool) {
-	for i := len(c.configs) - 1; i >= 0; i-- {
-		val := ""
-		config := c.configs[i]
-		if found := config.TryGet(key, &val); found {
-			return newEntryImpl(key, val, config.Source()), true
-		}
+	if entry, found := c.getSnapshot().entries[normalizeKey(c.queryKey(key))]; found {
+		return entry, true
 	}
 	return newEntryImpl(key, "", nil), false
 }
 
+// entryConfig is implemented by [config.Config] types which carry more
+// information about their values than just the source, e.g. resolved Key Vault
+// references. The returned entries are used as-is by [config.RootConfig].
+type entryConfig interface {
+	tryGetEntry(key string) (Entry, bool)
+}
+
+// getConfigEntry returns the entry for the key from the config, preserving
+// any additional information provided by [config.entryConfig].
+func getConfigEntry(config Config, key string) (Entry, bool) {
+	if ec, ok := config.(entryConfig); ok {
+		return ec.tryGetEntry(key)
+	}
+
+	val := ""
+	if found := config.TryGet(key, &val); found {
+		return newEntryImpl(key, val, config.Source()), true
+	}
+	return newEntryImpl(key, "", config.Source()), false
+}
+
 // Entry is a configuration entry combining key, value and the source of the value.
 type Entry interface {
 	Key() string
@@ -285,6 +576,14 @@ type Entry interface {
 	Source() Source
 }
 
+// SecretEntry is implemented by entries which values are secrets, e.g. values
+// decrypted from SOPS files. Tools should avoid printing these values.
+type SecretEntry interface {
+	Entry
+	// Secret reports whether the value is a secret.
+	Secret() bool
+}
+
 // newEntryImpl creates new instance of [config.Entry]
 func newEntryImpl(key string, value string, configSource Source) *configEntryImpl {
 	return &configEntryImpl{
@@ -312,3 +611,48 @@ func (c *configEntryImpl) Value() string {
 func (c *configEntryImpl) Source() Source {
 	return c.configSource
 }
+
+// secretEntryImpl implements [config.SecretEntry] interface.
+type secretEntryImpl struct {
+	Entry
+}
+
+func (e *secretEntryImpl) Secret() bool {
+	return true
+}
+
+// secretJsonEntryImpl implements [config.SecretEntry] and [config.JsonEntry]
+// interfaces, for secret values from Json.
+type secretJsonEntryImpl struct {
+	*jsonEntryImpl
+}
+
+func (e *secretJsonEntryImpl) Secret() bool {
+	return true
+}
+
+// secretConfig marks the values of some keys of the wrapped config as secret.
+type secretConfig struct {
+	Config
+	secretKeys map[string]bool
+}
+
+// newSecretConfig creates [config.Config] where the entries with normalised keys
+// in secretKeys implement [config.SecretEntry].
+func newSecretConfig(config Config, secretKeys map[string]bool) *secretConfig {
+	return &secretConfig{
+		Config:     config,
+		secretKeys: secretKeys,
+	}
+}
+
+func (c *secretConfig) tryGetEntry(key string) (Entry, bool) {
+	entry, found := getConfigEntry(c.Config, key)
+	if found && c.secretKeys[normalizeKey(key)] {
+		if e, ok := entry.(*jsonEntryImpl); ok {
+			return &secretJsonEntryImpl{e}, true
+		}
+		return &secretEntryImpl{entry}, true
+	}
+	return entry, found
+}
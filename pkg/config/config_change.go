@@ -0,0 +1,249 @@
+package config
+
+import (
+	"sort"
+	"sync"
+)
+
+// ConfigChangeKind is the kind of change of a key, see [config.ConfigChange].
+type ConfigChangeKind int
+
+// Kinds of changes reported by [config.RootConfig.OnChange].
+const (
+	// ConfigChangeAdded means the key did not exist before.
+	ConfigChangeAdded ConfigChangeKind = iota + 1
+	// ConfigChangeRemoved means the key does not exist anymore.
+	ConfigChangeRemoved
+	// ConfigChangeModified means the value or the source of the value has changed.
+	ConfigChangeModified
+)
+
+// String returns the name of the kind, e.g. "Added".
+func (k ConfigChangeKind) String() string {
+	switch k {
+	case ConfigChangeAdded:
+		return "Added"
+	case ConfigChangeRemoved:
+		return "Removed"
+	case ConfigChangeModified:
+		return "Modified"
+	default:
+		return "Unknown"
+	}
+}
+
+// ConfigChange describes the change of a key on reload.
+type ConfigChange struct {
+	// Key is the key with the casing of the first provider which has it.
+	Key  string
+	Kind ConfigChangeKind
+	// Old is the entry before the change, nil when the key was added.
+	Old Entry
+	// New is the entry after the change, nil when the key was removed.
+	New Entry
+}
+
+// diffEntries returns the changes between the entries, sorted by keys.
+func diffEntries(oldEntries map[string]Entry, newEntries map[string]Entry) []ConfigChange {
+	var changes []ConfigChange
+	for key, newEntry := range newEntries {
+		oldEntry, found := oldEntries[key]
+		switch {
+		case !found:
+			changes = append(changes, ConfigChange{Key: newEntry.Key(), Kind: ConfigChangeAdded, New: newEntry})
+		case oldEntry.Value() != newEntry.Value() || sourceName(oldEntry.Source()) != sourceName(newEntry.Source()):
+			changes = append(changes, ConfigChange{Key: newEntry.Key(), Kind: ConfigChangeModified, Old: oldEntry, New: newEntry})
+		}
+	}
+
+	for key, oldEntry := range oldEntries {
+		if _, found := newEntries[key]; !found {
+			changes = append(changes, ConfigChange{Key: oldEntry.Key(), Kind: ConfigChangeRemoved, Old: oldEntry})
+		}
+	}
+
+	sort.Slice(changes, func(i int, j int) bool {
+		return normalizeKey(changes[i].Key) < normalizeKey(changes[j].Key)
+	})
+	return changes
+}
+
+func sourceName(source Source) string {
+	if source == nil {
+		return ""
+	}
+	return source.Name()
+}
+
+func (c *rootConfigImpl) Reload() error {
+	c.reloadMu.Lock()
+	c.setReloading(true)
+	providers, err := c.reloader()
+	var changes []ConfigChange
+	if err == nil {
+		changes = c.setProviders(providers)
+	}
+	c.setReloading(false)
+	c.reloadMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	c.reloadToken.onReload()
+	c.notify(changes)
+	return nil
+}
+
+func (c *rootConfigImpl) GetReloadToken() ChangeToken {
+	return c.reloadToken.get()
+}
+
+func (c *rootConfigImpl) OnChange(callback func(changes []ConfigChange)) (unregister func()) {
+	c.callbacksMu.Lock()
+	defer c.callbacksMu.Unlock()
+
+	id := c.nextId
+	c.nextId++
+	c.callbacks[id] = callback
+
+	return func() {
+		c.callbacksMu.Lock()
+		defer c.callbacksMu.Unlock()
+		delete(c.callbacks, id)
+	}
+}
+
+// setProviders replaces all providers at once and returns the changes.
+func (c *rootConfigImpl) setProviders(providers []Provider) []ConfigChange {
+	snapshot := newRootSnapshot(providers)
+	watchers := make([]*providerWatcher, 0, len(providers))
+	for _, provider := range providers {
+		watchers = append(watchers, c.watchProvider(provider))
+	}
+
+	c.mu.Lock()
+	old := c.getSnapshot()
+	oldWatchers := c.watchers
+	c.snapshot.Store(snapshot)
+	c.watchers = watchers
+	c.mu.Unlock()
+
+	for _, w := range oldWatchers {
+		w.stop()
+	}
+
+	if old.entries == nil {
+		return nil
+	}
+	return diffEntries(old.entries, snapshot.entries)
+}
+
+func (c *rootConfigImpl) setReloading(reloading bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloading = reloading
+}
+
+// onProviderReload is invoked when a provider reloads by itself, e.g. when the
+// file is changed, and reports the changes.
+func (c *rootConfigImpl) onProviderReload() {
+	c.mu.RLock()
+	reloading := c.reloading
+	c.mu.RUnlock()
+	if reloading {
+		// Reload reports the changes when done.
+		return
+	}
+
+	changes := c.refresh()
+	c.reloadToken.onReload()
+	c.notify(changes)
+}
+
+// refresh replaces the snapshot with the current values of the same providers,
+// e.g. after a provider reloads or its values are set, and returns the changes.
+func (c *rootConfigImpl) refresh() []ConfigChange {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	return c.refreshLocked()
+}
+
+// refreshLocked is refresh for callers which hold reloadMu.
+func (c *rootConfigImpl) refreshLocked() []ConfigChange {
+	old := c.getSnapshot()
+	providers := old.providers
+	if c.derive != nil {
+		providers = c.derive(providers)
+	}
+	snapshot := newRootSnapshot(providers)
+	c.snapshot.Store(snapshot)
+	return diffEntries(old.entries, snapshot.entries)
+}
+
+// notify invokes OnChange callbacks in the order they were registered.
+func (c *rootConfigImpl) notify(changes []ConfigChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	c.callbacksMu.Lock()
+	callbacks := make([]func(changes []ConfigChange), 0, len(c.callbacks))
+	for id := 0; id < c.nextId; id++ {
+		if callback, found := c.callbacks[id]; found {
+			callbacks = append(callbacks, callback)
+		}
+	}
+	c.callbacksMu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(changes)
+	}
+}
+
+// providerWatcher follows the reload tokens of a provider, registering on each
+// new token, until stopped.
+type providerWatcher struct {
+	mu         sync.Mutex
+	stopped    bool
+	unregister func()
+}
+
+func (c *rootConfigImpl) watchProvider(provider Provider) *providerWatcher {
+	w := &providerWatcher{}
+
+	var register func()
+	register = func() {
+		unregister := provider.GetReloadToken().RegisterChangeCallback(func() {
+			if w.isStopped() {
+				return
+			}
+			c.onProviderReload()
+			register()
+		})
+
+		w.mu.Lock()
+		w.unregister = unregister
+		w.mu.Unlock()
+	}
+	register()
+
+	return w
+}
+
+func (w *providerWatcher) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+func (w *providerWatcher) stop() {
+	w.mu.Lock()
+	w.stopped = true
+	unregister := w.unregister
+	w.mu.Unlock()
+
+	if unregister != nil {
+		unregister()
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// mutableTestSource returns the current data on every Build.
+type mutableTestSource struct {
+	name string
+	mu   sync.Mutex
+	data map[string]string
+	err  error
+}
+
+func (s *mutableTestSource) set(data map[string]string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.err = err
+}
+
+func (s *mutableTestSource) Name() string {
+	return s.name
+}
+
+func (s *mutableTestSource) Build() (Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return newConfigImpl(s, s.data), nil
+}
+
+func Test_RootConfig_Reload(t *testing.T) {
+	first := &mutableTestSource{name: "first", data: map[string]string{"a": "1", "b": "1", "c": "1"}}
+	second := &mutableTestSource{name: "second", data: map[string]string{}}
+
+	builder := NewBuilder()
+	builder.AddSource(first)
+	builder.AddSource(second)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	var changes []ConfigChange
+	unregister := root.OnChange(func(c []ConfigChange) { changes = c })
+	token := root.GetReloadToken()
+
+	first.set(map[string]string{"a": "1", "b": "2", "d": "1"}, nil)
+	second.set(map[string]string{"a": "1"}, nil)
+	assert.NoError(t, root.Reload())
+	assert.True(t, token.HasChanged())
+
+	if assert.Len(t, changes, 4) {
+		assert.Equal(t, "a", changes[0].Key)
+		assert.Equal(t, ConfigChangeModified, changes[0].Kind)
+		assert.Equal(t, "first", changes[0].Old.Source().Name())
+		assert.Equal(t, "second", changes[0].New.Source().Name())
+
+		assert.Equal(t, "b", changes[1].Key)
+		assert.Equal(t, ConfigChangeModified, changes[1].Kind)
+		assert.Equal(t, "1", changes[1].Old.Value())
+		assert.Equal(t, "2", changes[1].New.Value())
+
+		assert.Equal(t, "c", changes[2].Key)
+		assert.Equal(t, ConfigChangeRemoved, changes[2].Kind)
+		assert.Nil(t, changes[2].New)
+
+		assert.Equal(t, "d", changes[3].Key)
+		assert.Equal(t, ConfigChangeAdded, changes[3].Kind)
+		assert.Nil(t, changes[3].Old)
+	}
+	assert.Equal(t, "2", root.Get("b"))
+
+	// No changes, no callback.
+	changes = nil
+	assert.NoError(t, root.Reload())
+	assert.Nil(t, changes)
+
+	unregister()
+	first.set(map[string]string{"a": "3"}, nil)
+	assert.NoError(t, root.Reload())
+	assert.Nil(t, changes)
+}
+
+func Test_RootConfig_ReloadError(t *testing.T) {
+	first := &mutableTestSource{name: "first", data: map[string]string{"a": "1"}}
+	second := &mutableTestSource{name: "second", data: map[string]string{"b": "1"}}
+
+	builder := NewBuilder()
+	builder.AddSource(first)
+	builder.AddSource(second)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	first.set(map[string]string{"a": "2"}, nil)
+	second.set(nil, errors.New("broken"))
+	assert.Error(t, root.Reload())
+
+	// Nothing is changed, including the first source.
+	assert.Equal(t, "1", root.Get("a"))
+	assert.Equal(t, "1", root.Get("b"))
+}
+
+func Test_RootConfig_ProviderReload(t *testing.T) {
+	source := &mutableTestSource{name: "source", data: map[string]string{"a": "1"}}
+	builder := NewBuilder()
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	var changes []ConfigChange
+	root.OnChange(func(c []ConfigChange) { changes = c })
+
+	// The provider reloads by itself, e.g. when the file is changed.
+	source.set(map[string]string{"a": "2"}, nil)
+	assert.NoError(t, root.Providers()[0].Load())
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "2", changes[0].New.Value())
+	}
+
+	// Again, with the new reload token.
+	source.set(map[string]string{"a": "3"}, nil)
+	assert.NoError(t, root.Providers()[0].Load())
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "3", changes[0].New.Value())
+	}
+
+	// Providers replaced by Reload are not watched anymore.
+	old := root.Providers()[0]
+	assert.NoError(t, root.Reload())
+	changes = nil
+	source.set(map[string]string{"a": "4"}, nil)
+	assert.NoError(t, old.Load())
+	assert.Nil(t, changes)
+	assert.Equal(t, "3", root.Get("a"))
+}
+
+func Test_RootConfig_ReloadConsistentReads(t *testing.T) {
+	first := &mutableTestSource{name: "first", data: map[string]string{"a": "0"}}
+	second := &mutableTestSource{name: "second", data: map[string]string{"b": "0"}}
+
+	builder := NewBuilder()
+	builder.AddSource(first)
+	builder.AddSource(second)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	var done int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&done) == 0 {
+				entries := root.GetEntries()
+				if len(entries) != 2 || entries[0].Value() != entries[1].Value() {
+					t.Errorf("mixed layers: %v", entries)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= 100; i++ {
+		value := string(rune('0' + i%10))
+		first.set(map[string]string{"a": value}, nil)
+		second.set(map[string]string{"b": value}, nil)
+		assert.NoError(t, root.Reload())
+	}
+	atomic.StoreInt32(&done, 1)
+	wg.Wait()
+}
+
+func Test_ResolveKeyVaultReferences_Reload(t *testing.T) {
+	source := &mutableTestSource{name: "source", data: map[string]string{
+		"Secret": "@Microsoft.KeyVault(VaultName=vault;SecretName=one)",
+	}}
+	builder := NewBuilder()
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	resolver := NewKeyVaultMapResolver(map[string]map[string]string{
+		"vault": {"one": "first", "two": "second"},
+	})
+	resolved, err := ResolveKeyVaultReferences(root, resolver)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", resolved.Get("Secret"))
+
+	source.set(map[string]string{"Secret": "@Microsoft.KeyVault(VaultName=vault;SecretName=two)"}, nil)
+	assert.NoError(t, resolved.Reload())
+	assert.Equal(t, "second", resolved.Get("Secret"))
+
+	// The root which was passed in is not reloaded.
+	assert.Equal(t, "@Microsoft.KeyVault(VaultName=vault;SecretName=one)", root.Get("Secret"))
+}
+
+func Test_ResolveKeyVaultReferences_ProviderReload(t *testing.T) {
+	source := &mutableTestSource{name: "source", data: map[string]string{
+		"Secret": "@Microsoft.KeyVault(VaultName=vault;SecretName=one)",
+	}}
+	builder := NewBuilder()
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	resolver := NewKeyVaultMapResolver(map[string]map[string]string{
+		"vault": {"one": "first", "two": "second"},
+	})
+	resolved, err := ResolveKeyVaultReferences(root, resolver)
+	assert.NoError(t, err)
+
+	var changes []ConfigChange
+	resolved.OnChange(func(c []ConfigChange) { changes = c })
+
+	// The provider of the resolved root reloads by itself.
+	source.set(map[string]string{
+		"Secret": "@Microsoft.KeyVault(VaultName=vault;SecretName=two)",
+		"Other":  "@Microsoft.KeyVault(VaultName=vault;SecretName=one)",
+	}, nil)
+	assert.NoError(t, resolved.Providers()[0].Load())
+
+	assert.Equal(t, "second", resolved.Get("Secret"))
+	assert.Equal(t, "first", resolved.Get("Other"))
+	_, ok := resolved.GetEntry("Other").(KeyVaultEntry)
+	assert.True(t, ok)
+	assert.Len(t, changes, 2)
+
+	// The root which was passed in, and its providers, are unchanged.
+	assert.Equal(t, "@Microsoft.KeyVault(VaultName=vault;SecretName=one)", root.Get("Secret"))
+	assert.Equal(t, "", root.Get("Other"))
+	assert.Equal(t, []string{"Secret"}, root.Providers()[0].Keys())
+}
+
+func Test_ResolveKeyVaultReferences_Diagnostics(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{"A__B": "value"}))
+	builder.AddSource(&mutableTestSource{name: "failing", err: errors.New("failed")})
+	root, err := builder.BuildWithOptions(BuildOptions{ContinueOnError: true, LegacyKeyNormalization: true})
+	assert.Error(t, err)
+
+	resolved, err := ResolveKeyVaultReferences(root, NewKeyVaultMapResolver(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, root.Diagnostics(), resolved.Diagnostics())
+	assert.Equal(t, "value", resolved.Get("A__B"))
+}
@@ -70,8 +70,8 @@ func ExampleBuilder_Build_jsonMerge() {
 	}
 
 	// Output:
-	// appname = app name from the appsettings.json
-	// connectionsstrings:logger = logger connection string from appsettings.Development.json
-	// connectionsstrings:redis = redis connection string from appsettings.Development.json
-	// connectionsstrings:sql = sql connection string from appsettings.json
+	// AppName = app name from the appsettings.json
+	// ConnectionsStrings:Logger = logger connection string from appsettings.Development.json
+	// ConnectionsStrings:Redis = redis connection string from appsettings.Development.json
+	// ConnectionsStrings:Sql = sql connection string from appsettings.json
 }
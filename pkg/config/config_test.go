@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sourceNames(sources []Source) []string {
+	var names []string
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	return names
+}
+
+func Test_Builder_Sources(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"a": "json"}`)).WithName("json"))
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{"a": "env"}).WithName("env"))
+	assert.Equal(t, []string{"json", "env"}, sourceNames(builder.Sources()))
+
+	assert.NoError(t, builder.InsertSource(0, NewJsonSource([]byte(`{"a": "first"}`)).WithName("first")))
+	assert.NoError(t, builder.InsertSource(3, NewJsonSource([]byte(`{"a": "last"}`)).WithName("last")))
+	assert.Error(t, builder.InsertSource(5, NewJsonSource(nil)))
+	assert.Error(t, builder.InsertSource(-1, NewJsonSource(nil)))
+	assert.Equal(t, []string{"first", "json", "env", "last"}, sourceNames(builder.Sources()))
+
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "last", root.Get("a"))
+
+	assert.True(t, builder.RemoveSource("last"))
+	assert.False(t, builder.RemoveSource("last"))
+	assert.Equal(t, []string{"first", "json", "env"}, sourceNames(builder.Sources()))
+
+	// Sources returns a copy.
+	builder.Sources()[0] = nil
+	assert.Equal(t, "first", builder.Sources()[0].Name())
+
+	root, err = builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "env", root.Get("a"))
+
+	source, ok := root.Source().(*CompositeSource)
+	if assert.True(t, ok) {
+		assert.Equal(t, "CompositeSource: first, json, env", source.Name())
+		assert.Equal(t, []string{"first", "json", "env"}, sourceNames(source.Sources()))
+
+		config, err := source.Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "env", config.Get("a"))
+	}
+}
+
+func Test_Builder_Properties(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "appsettings.json"), []byte(`{"a": "os"}`), 0600))
+
+	builder := NewBuilder()
+	builder.Properties()[PropertyBasePath] = dir
+	builder.AddSource(NewFileSource("appsettings.json"))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "os", root.Get("a"))
+
+	// File system takes precedence over base path.
+	builder.Properties()[PropertyFileSystem] = fstest.MapFS{
+		"appsettings.json": {Data: []byte(`{"a": "fs"}`)},
+	}
+	root, err = builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "fs", root.Get("a"))
+
+	// Explicit file system is not replaced.
+	builder = NewBuilder()
+	builder.Properties()[PropertyFileSystem] = fstest.MapFS{}
+	builder.AddSource(NewFileSource("appsettings.json").WithFileSystem(os.DirFS(dir)))
+	root, err = builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "os", root.Get("a"))
+}
+
+func Test_KeyNormalization_PerSource(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"a__b": "json", "Logging": {"Level": "Info"}}`)))
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{"Logging__Level": "Debug"}))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	// Only environment variables have "__" converted to the delimiter.
+	assert.Equal(t, []string{"a__b", "Logging:Level"}, root.Keys())
+	assert.Equal(t, "json", root.Get("A__B"))
+	assert.False(t, root.TryGet("a:b", new(string)))
+	assert.Equal(t, "Debug", root.Get("logging:level"))
+	assert.False(t, root.TryGet("logging__level", new(string)))
+}
@@ -0,0 +1,365 @@
+package config
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Media types of OCI and Docker image indexes.
+const (
+	ociImageIndexMediaType      = "application/vnd.oci.image.index.v1+json"
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// LoadContainerImage loads the container image from a tarball created by
+// "docker save" or from an OCI image-layout tarball, entirely offline.
+//
+// The ref selects the image when the tarball has several, e.g. "myapp:1.0".
+// Empty ref selects the first image. In OCI image layout, the ref must be equal
+// to the full image name, e.g. "docker.io/library/myapp:1.0", or to the ref
+// name annotation of the image, e.g. "1.0".
+//
+// The tarball is kept open to read the files lazily, so the image must be
+// closed when no longer needed.
+func LoadContainerImage(tarballPath string, ref string) (*ContainerImage, error) {
+	file, err := os.Open(tarballPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "LoadContainerImage")
+	}
+
+	img := &ContainerImage{file: file}
+	if err := img.load(ref); err != nil {
+		_ = file.Close()
+		return nil, errors.Wrapf(err, "LoadContainerImage: %s", tarballPath)
+	}
+	return img, nil
+}
+
+// ContainerImage is a container image loaded with [config.LoadContainerImage].
+//
+// It gives access to the image config (Env, Cmd, Entrypoint, WorkingDir)
+// and to the final file system of the image, with all layers applied in order.
+type ContainerImage struct {
+	file    *os.File
+	members map[string]tarMember
+	name    string
+	config  containerImageConfig
+	fs      *imageFS
+}
+
+// tarMember is the location of a file inside the outer tarball.
+type tarMember struct {
+	offset int64
+	size   int64
+}
+
+// containerImageConfig is the subset of the OCI image config we need.
+type containerImageConfig struct {
+	Config struct {
+		Env        []string `json:"Env"`
+		Cmd        []string `json:"Cmd"`
+		Entrypoint []string `json:"Entrypoint"`
+		WorkingDir string   `json:"WorkingDir"`
+	} `json:"config"`
+}
+
+// Name is the name of the image, e.g. "myapp:1.0", or the digest of its config
+// if the image has no name.
+func (img *ContainerImage) Name() string {
+	return img.name
+}
+
+// Env returns the environment variables of the image as "KEY=value" strings.
+func (img *ContainerImage) Env() []string {
+	return img.config.Config.Env
+}
+
+// EnvMap returns the environment variables of the image as a map.
+func (img *ContainerImage) EnvMap() map[string]string {
+	m := make(map[string]string, len(img.config.Config.Env))
+	for _, val := range img.config.Config.Env {
+		fields := strings.SplitN(val, "=", 2)
+		if len(fields) == 2 {
+			m[fields[0]] = fields[1]
+		} else {
+			m[fields[0]] = ""
+		}
+	}
+	return m
+}
+
+// Cmd returns the Cmd of the image.
+func (img *ContainerImage) Cmd() []string {
+	return img.config.Config.Cmd
+}
+
+// Entrypoint returns the Entrypoint of the image.
+func (img *ContainerImage) Entrypoint() []string {
+	return img.config.Config.Entrypoint
+}
+
+// WorkingDir returns the working directory of the image, "/" if not set.
+func (img *ContainerImage) WorkingDir() string {
+	if img.config.Config.WorkingDir == "" {
+		return "/"
+	}
+	return img.config.Config.WorkingDir
+}
+
+// Environment returns the ASP.NET environment name of the image in the same
+// way ASP.NET determines it: ASPNETCORE_ENVIRONMENT, then DOTNET_ENVIRONMENT,
+// then "Production".
+func (img *ContainerImage) Environment() string {
+	env := img.EnvMap()
+	for _, name := range []string{"ASPNETCORE_ENVIRONMENT", "DOTNET_ENVIRONMENT"} {
+		if val := env[name]; val != "" {
+			return val
+		}
+	}
+	return "Production"
+}
+
+// FS returns the final file system of the image. The paths are relative to
+// the root of the image, e.g. "app/appsettings.json".
+func (img *ContainerImage) FS() fs.FS {
+	return img.fs
+}
+
+// Builder returns [config.Builder] with the sources in the same order as
+// ASP.NET WebApplication adds them, when the app runs in the working directory
+// of the image:
+//
+//	- Environment variables with "DOTNET_" prefix.
+//	- Environment variables with "ASPNETCORE_" prefix.
+//	- appsettings.json, if it exists.
+//	- appsettings.{Environment}.json, if it exists.
+//	- Environment variables.
+//
+// Command line arguments in Cmd and Entrypoint are not applied.
+func (img *ContainerImage) Builder() Builder {
+	env := img.EnvMap()
+	contentRoot := strings.TrimPrefix(path.Clean(img.WorkingDir()), "/")
+	if contentRoot == "" {
+		contentRoot = "."
+	}
+
+	builder := NewBuilder()
+	builder.AddSource(NewEnvVarsMapSource("DOTNET_", env).WithName(fmt.Sprintf("%s Env Prefix: 'DOTNET_'", img.name)))
+	builder.AddSource(NewEnvVarsMapSource("ASPNETCORE_", env).WithName(fmt.Sprintf("%s Env Prefix: 'ASPNETCORE_'", img.name)))
+
+	for _, file := range []string{"appsettings.json", fmt.Sprintf("appsettings.%s.json", img.Environment())} {
+		filePath := path.Join(contentRoot, file)
+		if info, err := fs.Stat(img.fs, filePath); err == nil && !info.IsDir() {
+			builder.AddSource(NewJsonFileSource(img.fs, filePath))
+		}
+	}
+
+	builder.AddSource(NewEnvVarsMapSource("", env).WithName(fmt.Sprintf("%s Env", img.name)))
+	return builder
+}
+
+// Close closes the tarball.
+func (img *ContainerImage) Close() error {
+	return img.file.Close()
+}
+
+func (img *ContainerImage) load(ref string) error {
+	if err := img.indexMembers(); err != nil {
+		return err
+	}
+
+	var configPath string
+	var layerPaths []string
+	var err error
+	if _, found := img.members["manifest.json"]; found {
+		configPath, layerPaths, err = img.loadDockerManifest(ref)
+	} else if _, found := img.members["index.json"]; found {
+		configPath, layerPaths, err = img.loadOciIndex(ref)
+	} else {
+		err = errors.New("neither manifest.json nor index.json found, not an image tarball")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := img.readJson(configPath, &img.config); err != nil {
+		return errors.Wrap(err, "cannot read image config")
+	}
+
+	if img.name == "" {
+		img.name = path.Base(configPath)
+	}
+
+	img.fs = newImageFS(img)
+	for i, layerPath := range layerPaths {
+		if err := img.fs.applyLayer(i, layerPath); err != nil {
+			return errors.Wrapf(err, "layer %s", layerPath)
+		}
+	}
+	img.fs.buildChildren()
+	return nil
+}
+
+// indexMembers records the offsets of all files in the outer tarball, so
+// they can be read later without scanning the tarball again.
+func (img *ContainerImage) indexMembers() error {
+	img.members = make(map[string]tarMember)
+	cr := &countingReader{r: img.file}
+	tr := tar.NewReader(cr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if hdr.Typeflag == tar.TypeReg || hdr.Typeflag == tar.TypeRegA {
+			img.members[cleanTarPath(hdr.Name)] = tarMember{offset: cr.n, size: hdr.Size}
+		}
+	}
+}
+
+func (img *ContainerImage) openMember(name string) (*io.SectionReader, error) {
+	member, found := img.members[cleanTarPath(name)]
+	if !found {
+		return nil, errors.Errorf("'%s' not found in the tarball", name)
+	}
+	return io.NewSectionReader(img.file, member.offset, member.size), nil
+}
+
+func (img *ContainerImage) readJson(name string, v interface{}) error {
+	r, err := img.openMember(name)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(r).Decode(v)
+}
+
+// loadDockerManifest reads manifest.json of "docker save" tarball.
+func (img *ContainerImage) loadDockerManifest(ref string) (string, []string, error) {
+	var manifests []struct {
+		Config   string   `json:"Config"`
+		RepoTags []string `json:"RepoTags"`
+		Layers   []string `json:"Layers"`
+	}
+	if err := img.readJson("manifest.json", &manifests); err != nil {
+		return "", nil, errors.Wrap(err, "cannot read manifest.json")
+	}
+
+	for _, manifest := range manifests {
+		if ref == "" {
+			if len(manifest.RepoTags) > 0 {
+				img.name = manifest.RepoTags[0]
+			}
+			return manifest.Config, manifest.Layers, nil
+		}
+		for _, tag := range manifest.RepoTags {
+			if tag == ref {
+				img.name = tag
+				return manifest.Config, manifest.Layers, nil
+			}
+		}
+	}
+
+	return "", nil, errors.Errorf("image '%s' not found in manifest.json", ref)
+}
+
+// ociDescriptor is OCI content descriptor.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// loadOciIndex reads index.json of OCI image layout. Nested indexes are
+// followed, and the first image manifest is used.
+func (img *ContainerImage) loadOciIndex(ref string) (string, []string, error) {
+	var index struct {
+		Manifests []ociDescriptor `json:"manifests"`
+	}
+	if err := img.readJson("index.json", &index); err != nil {
+		return "", nil, errors.Wrap(err, "cannot read index.json")
+	}
+
+	for _, desc := range index.Manifests {
+		name := desc.Annotations["io.containerd.image.name"]
+		refName := desc.Annotations["org.opencontainers.image.ref.name"]
+		if ref != "" && name != ref && refName != ref {
+			continue
+		}
+		if name == "" {
+			name = refName
+		}
+
+		img.name = name
+		return img.loadOciManifest(desc)
+	}
+
+	return "", nil, errors.Errorf("image '%s' not found in index.json", ref)
+}
+
+func (img *ContainerImage) loadOciManifest(desc ociDescriptor) (string, []string, error) {
+	var manifest struct {
+		MediaType string          `json:"mediaType"`
+		Manifests []ociDescriptor `json:"manifests"`
+		Config    ociDescriptor   `json:"config"`
+		Layers    []ociDescriptor `json:"layers"`
+	}
+	if err := img.readJson(ociBlobPath(desc.Digest), &manifest); err != nil {
+		return "", nil, errors.Wrapf(err, "cannot read manifest %s", desc.Digest)
+	}
+
+	if desc.MediaType == ociImageIndexMediaType || desc.MediaType == dockerManifestListMediaType || len(manifest.Manifests) > 0 {
+		for _, nested := range manifest.Manifests {
+			if nested.Annotations["vnd.docker.reference.type"] == "attestation-manifest" {
+				continue
+			}
+			return img.loadOciManifest(nested)
+		}
+		return "", nil, errors.Errorf("index %s has no image manifests", desc.Digest)
+	}
+
+	var layers []string
+	for _, layer := range manifest.Layers {
+		layers = append(layers, ociBlobPath(layer.Digest))
+	}
+	return ociBlobPath(manifest.Config.Digest), layers, nil
+}
+
+// ociBlobPath returns the path of the blob in OCI image layout, e.g.
+// "sha256:abc" is stored in "blobs/sha256/abc".
+func ociBlobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+// cleanTarPath cleans the name of a tar entry, e.g. "./app/" becomes "app".
+// The root is ".".
+func cleanTarPath(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,386 @@
+package config
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Whiteout files mark deletions in image layers.
+//
+// See: https://github.com/opencontainers/image-spec/blob/main/layer.md#whiteouts
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// maxSymlinkHops limits the number of symlinks followed when resolving a path.
+const maxSymlinkHops = 40
+
+// imageFS is the final file system of [config.ContainerImage], with all layers
+// applied in order. It keeps only the metadata of files, the content is read
+// from the layer on demand.
+type imageFS struct {
+	img      *ContainerImage
+	layers   []string
+	nodes    map[string]*imageNode
+	children map[string][]string
+}
+
+// imageNode is a file, directory or link in the image.
+type imageNode struct {
+	path         string
+	mode         fs.FileMode
+	size         int64
+	modTime      time.Time
+	layer        int
+	tarName      string
+	linkTarget   string
+	danglingLink bool
+}
+
+func newImageFS(img *ContainerImage) *imageFS {
+	return &imageFS{
+		img: img,
+		nodes: map[string]*imageNode{
+			".": {path: ".", mode: fs.ModeDir | 0755},
+		},
+	}
+}
+
+// applyLayer applies the layer on top of the current file system. Whiteouts
+// only affect lower layers, so they are applied before the files of the layer.
+func (f *imageFS) applyLayer(index int, layerPath string) error {
+	f.layers = append(f.layers, layerPath)
+
+	var headers []*tar.Header
+	err := f.scanLayer(index, func(hdr *tar.Header, _ io.Reader) (bool, error) {
+		headers = append(headers, hdr)
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hdr := range headers {
+		name := cleanTarPath(hdr.Name)
+		dir, base := path.Split(name)
+		dir = cleanTarPath(dir)
+		switch {
+		case base == whiteoutOpaque:
+			f.removeChildren(dir)
+		case strings.HasPrefix(base, whiteoutPrefix):
+			f.remove(path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+		}
+	}
+
+	for _, hdr := range headers {
+		name := cleanTarPath(hdr.Name)
+		if strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+			continue
+		}
+
+		node := &imageNode{
+			path:    name,
+			mode:    hdr.FileInfo().Mode(),
+			size:    hdr.Size,
+			modTime: hdr.ModTime,
+			layer:   index,
+			tarName: hdr.Name,
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if existing := f.nodes[name]; existing != nil && existing.mode.IsDir() {
+				existing.mode, existing.modTime = node.mode, node.modTime
+				continue
+			}
+		case tar.TypeSymlink:
+			node.linkTarget = hdr.Linkname
+		case tar.TypeLink:
+			// The target is an earlier file of the same layer, which the
+			// later layers may replace without affecting the link.
+			node.linkTarget = cleanTarPath(hdr.Linkname)
+			if target := f.nodes[node.linkTarget]; target != nil && target.layer == index && target.mode.IsRegular() {
+				node.tarName, node.size = target.tarName, target.size
+			} else {
+				node.danglingLink = true
+			}
+		case tar.TypeReg, tar.TypeRegA:
+		default:
+			// devices, fifos etc. are of no interest
+			continue
+		}
+
+		f.add(node)
+	}
+
+	return nil
+}
+
+// add adds the node replacing anything at its path, and creates missing parents.
+func (f *imageFS) add(node *imageNode) {
+	if existing := f.nodes[node.path]; existing != nil && existing.mode.IsDir() && !node.mode.IsDir() {
+		f.removeChildren(node.path)
+	}
+	f.nodes[node.path] = node
+
+	for dir := path.Dir(node.path); dir != "."; dir = path.Dir(dir) {
+		if existing := f.nodes[dir]; existing != nil && existing.mode.IsDir() {
+			break
+		}
+		f.nodes[dir] = &imageNode{path: dir, mode: fs.ModeDir | 0755, modTime: node.modTime, layer: node.layer}
+	}
+}
+
+func (f *imageFS) remove(name string) {
+	f.removeChildren(name)
+	delete(f.nodes, name)
+}
+
+func (f *imageFS) removeChildren(dir string) {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	for name := range f.nodes {
+		if name != "." && name != dir && strings.HasPrefix(name, prefix) {
+			delete(f.nodes, name)
+		}
+	}
+}
+
+// buildChildren builds directory listings once all layers are applied.
+func (f *imageFS) buildChildren() {
+	f.children = make(map[string][]string)
+	for name := range f.nodes {
+		if name == "." {
+			continue
+		}
+		dir := path.Dir(name)
+		f.children[dir] = append(f.children[dir], name)
+	}
+	for _, names := range f.children {
+		sort.Strings(names)
+	}
+}
+
+// scanLayer invokes fn for every entry of the layer, until fn returns false.
+// The layers can be plain or gzip-compressed tarballs.
+func (f *imageFS) scanLayer(index int, fn func(hdr *tar.Header, r io.Reader) (bool, error)) error {
+	section, err := f.img.openMember(f.layers[index])
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(section)
+	magic, _ := br.Peek(4)
+
+	var r io.Reader = br
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return errors.New("zstd compressed layers are not supported")
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		more, err := fn(hdr, tr)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// resolve finds the node for the name following symlinks, including the last
+// path element when followLast is set. Symlinks cannot point outside of the image.
+func (f *imageFS) resolve(op string, name string, followLast bool) (*imageNode, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	current := name
+	for hops := 0; hops <= maxSymlinkHops; hops++ {
+		if current == "." {
+			return f.nodes["."], nil
+		}
+
+		parts := strings.Split(current, "/")
+		resolved := ""
+		for i, part := range parts {
+			p := path.Join(resolved, part)
+			node := f.nodes[p]
+			if node == nil {
+				return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+
+			isLast := i == len(parts)-1
+			if node.mode&fs.ModeSymlink != 0 && (followLast || !isLast) {
+				target := node.linkTarget
+				if !strings.HasPrefix(target, "/") {
+					target = path.Join(path.Dir(p), target)
+				}
+				current = cleanTarPath(path.Join(append([]string{target}, parts[i+1:]...)...))
+				resolved = ""
+				break
+			}
+
+			if isLast {
+				return node, nil
+			}
+			if !node.mode.IsDir() {
+				return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+			}
+			resolved = p
+		}
+	}
+
+	return nil, &fs.PathError{Op: op, Path: name, Err: errors.New("too many levels of symbolic links")}
+}
+
+// Open opens the named file. Part of [fs.FS] interface.
+func (f *imageFS) Open(name string) (fs.File, error) {
+	node, err := f.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &imageFileInfo{node: node, name: path.Base(name)}
+	if node.mode.IsDir() {
+		entries, err := f.readDir(node)
+		if err != nil {
+			return nil, err
+		}
+		return &memDir{info: info, entries: entries}, nil
+	}
+
+	data, err := f.readContent(node)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &memFile{info: info, r: bytes.NewReader(data)}, nil
+}
+
+// ReadDir reads the named directory. Part of [fs.ReadDirFS] interface.
+func (f *imageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, err := f.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !node.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return f.readDir(node)
+}
+
+// Stat returns the info of the named file. Part of [fs.StatFS] interface.
+func (f *imageFS) Stat(name string) (fs.FileInfo, error) {
+	node, err := f.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return &imageFileInfo{node: node, name: path.Base(name)}, nil
+}
+
+// ReadLink returns the target of the symbolic link.
+func (f *imageFS) ReadLink(name string) (string, error) {
+	node, err := f.resolve("readlink", name, false)
+	if err != nil {
+		return "", err
+	}
+	if node.mode&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	return node.linkTarget, nil
+}
+
+// Lstat returns the info of the named file without following the last symbolic link.
+func (f *imageFS) Lstat(name string) (fs.FileInfo, error) {
+	node, err := f.resolve("lstat", name, false)
+	if err != nil {
+		return nil, err
+	}
+	return &imageFileInfo{node: node, name: path.Base(name)}, nil
+}
+
+func (f *imageFS) displayName(path string) string {
+	return fmt.Sprintf("%s:/%s", f.img.name, path)
+}
+
+func (f *imageFS) readDir(dir *imageNode) ([]fs.DirEntry, error) {
+	names := f.children[dir.path]
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, name := range names {
+		node := f.nodes[name]
+		entries = append(entries, &imageFileInfo{node: node, name: path.Base(name)})
+	}
+	return entries, nil
+}
+
+// readContent reads the content of the file from its layer. Hard links are
+// read from their target, which is resolved when the layer is applied.
+func (f *imageFS) readContent(node *imageNode) ([]byte, error) {
+	if node.danglingLink {
+		return nil, errors.Errorf("hard link target '%s' not found in layer %s", node.linkTarget, f.layers[node.layer])
+	}
+
+	layer, tarName := node.layer, node.tarName
+
+	var data []byte
+	found := false
+	err := f.scanLayer(layer, func(hdr *tar.Header, r io.Reader) (bool, error) {
+		if hdr.Name != tarName {
+			return true, nil
+		}
+		var err error
+		data, err = io.ReadAll(r)
+		found = true
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.Errorf("'%s' not found in layer %s", tarName, f.layers[layer])
+	}
+	return data, nil
+}
+
+// imageFileInfo implements [fs.FileInfo] and [fs.DirEntry] interfaces.
+type imageFileInfo struct {
+	node *imageNode
+	name string
+}
+
+func (i *imageFileInfo) Name() string               { return i.name }
+func (i *imageFileInfo) Size() int64                { return i.node.size }
+func (i *imageFileInfo) Mode() fs.FileMode          { return i.node.mode }
+func (i *imageFileInfo) ModTime() time.Time         { return i.node.modTime }
+func (i *imageFileInfo) IsDir() bool                { return i.node.mode.IsDir() }
+func (i *imageFileInfo) Sys() interface{}           { return nil }
+func (i *imageFileInfo) Type() fs.FileMode          { return i.node.mode.Type() }
+func (i *imageFileInfo) Info() (fs.FileInfo, error) { return i, nil }
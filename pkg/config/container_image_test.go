@@ -0,0 +1,301 @@
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// imageTestEntry is an entry of a test tarball.
+type imageTestEntry struct {
+	name     string
+	content  string
+	typeflag byte
+	linkname string
+}
+
+func imageTestTar(t *testing.T, entries []imageTestEntry, compress bool) []byte {
+	buf := &bytes.Buffer{}
+	var tw *tar.Writer
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(buf)
+	}
+
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: e.typeflag, Linkname: e.linkname}
+		switch e.typeflag {
+		case tar.TypeDir:
+			hdr.Mode = 0755
+		case tar.TypeReg:
+			hdr.Size = int64(len(e.content))
+		}
+		assert.NoError(t, tw.WriteHeader(hdr))
+		if e.typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(e.content))
+			assert.NoError(t, err)
+		}
+	}
+
+	assert.NoError(t, tw.Close())
+	if gz != nil {
+		assert.NoError(t, gz.Close())
+	}
+	return buf.Bytes()
+}
+
+func imageTestFile(name string, content string) imageTestEntry {
+	return imageTestEntry{name: name, content: content, typeflag: tar.TypeReg}
+}
+
+func imageTestDir(name string) imageTestEntry {
+	return imageTestEntry{name: name, typeflag: tar.TypeDir}
+}
+
+var imageTestConfig = []byte(`{
+	"architecture": "amd64",
+	"os": "linux",
+	"config": {
+		"Env": ["PATH=/usr/bin", "ASPNETCORE_ENVIRONMENT=Staging", "ASPNETCORE_URLS=http://+:8080", "Logging__Level=Debug"],
+		"Entrypoint": ["dotnet", "MyApp.dll"],
+		"WorkingDir": "/app"
+	}
+}`)
+
+func imageTestLayers(t *testing.T) [][]byte {
+	base := imageTestTar(t, []imageTestEntry{
+		imageTestDir("app/"),
+		imageTestFile("app/appsettings.json", `{"Logging": {"Level": "Info"}, "AppName": "base"}`),
+		imageTestFile("app/appsettings.Development.json", `{"Logging": {"Level": "Trace"}}`),
+		imageTestFile("app/old.json", `{}`),
+		imageTestDir("etc/old/"),
+		imageTestFile("etc/old/a.conf", "a"),
+		imageTestFile("etc/keep.conf", "keep"),
+	}, false)
+
+	top := imageTestTar(t, []imageTestEntry{
+		imageTestFile("app/.wh.old.json", ""),
+		imageTestFile("etc/old/.wh..wh..opq", ""),
+		imageTestFile("etc/old/b.conf", "b"),
+		imageTestFile("app/appsettings.Staging.json", `{"Logging": {"Level": "Warning"}, "Staging": true}`),
+		{name: "app/link.json", typeflag: tar.TypeSymlink, linkname: "/app/appsettings.json"},
+		{name: "app/hard.json", typeflag: tar.TypeLink, linkname: "app/appsettings.Staging.json"},
+	}, true)
+
+	return [][]byte{base, top}
+}
+
+func imageTestDigest(b []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+// imageTestDockerSave writes a tarball in "docker save" format.
+func imageTestDockerSave(t *testing.T) string {
+	return imageTestDockerSaveLayers(t, imageTestLayers(t))
+}
+
+// imageTestDockerSaveLayers writes a tarball in "docker save" format with
+// two layers.
+func imageTestDockerSaveLayers(t *testing.T, layers [][]byte) string {
+	manifest, _ := json.Marshal([]map[string]interface{}{{
+		"Config":   "config.json",
+		"RepoTags": []string{"myapp:1.0"},
+		"Layers":   []string{"l1/layer.tar", "l2/layer.tar"},
+	}})
+
+	outer := imageTestTar(t, []imageTestEntry{
+		imageTestFile("manifest.json", string(manifest)),
+		imageTestFile("config.json", string(imageTestConfig)),
+		imageTestDir("l1/"),
+		imageTestFile("l1/layer.tar", string(layers[0])),
+		imageTestDir("l2/"),
+		imageTestFile("l2/layer.tar", string(layers[1])),
+	}, false)
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	assert.NoError(t, os.WriteFile(path, outer, 0600))
+	return path
+}
+
+// imageTestOci writes a tarball in OCI image layout format, with nested index.
+func imageTestOci(t *testing.T) string {
+	return imageTestOciAnnotations(t, map[string]string{"io.containerd.image.name": "docker.io/library/myapp:1.0"})
+}
+
+// imageTestOciAnnotations writes a tarball in OCI image layout format, with
+// the annotations of the image in the index.
+func imageTestOciAnnotations(t *testing.T, annotations map[string]string) string {
+	layers := imageTestLayers(t)
+	blob := func(b []byte) (string, imageTestEntry) {
+		d := imageTestDigest(b)
+		return d, imageTestFile(ociBlobPath(d), string(b))
+	}
+
+	configDigest, configEntry := blob(imageTestConfig)
+	layer1Digest, layer1Entry := blob(layers[0])
+	layer2Digest, layer2Entry := blob(layers[1])
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"config":    map[string]string{"digest": configDigest},
+		"layers": []map[string]string{
+			{"digest": layer1Digest, "mediaType": "application/vnd.oci.image.layer.v1.tar"},
+			{"digest": layer2Digest, "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip"},
+		},
+	})
+	manifestDigest, manifestEntry := blob(manifest)
+	nestedIndex, _ := json.Marshal(map[string]interface{}{
+		"manifests": []map[string]string{{"digest": manifestDigest, "mediaType": "application/vnd.oci.image.manifest.v1+json"}},
+	})
+	nestedIndexDigest, nestedIndexEntry := blob(nestedIndex)
+	index, _ := json.Marshal(map[string]interface{}{
+		"manifests": []map[string]interface{}{{
+			"digest":      nestedIndexDigest,
+			"mediaType":   ociImageIndexMediaType,
+			"annotations": annotations,
+		}},
+	})
+
+	outer := imageTestTar(t, []imageTestEntry{
+		imageTestFile("oci-layout", `{"imageLayoutVersion": "1.0.0"}`),
+		imageTestFile("index.json", string(index)),
+		configEntry, layer1Entry, layer2Entry, manifestEntry, nestedIndexEntry,
+	}, false)
+
+	path := filepath.Join(t.TempDir(), "image-oci.tar")
+	assert.NoError(t, os.WriteFile(path, outer, 0600))
+	return path
+}
+
+func Test_ContainerImage_Formats(t *testing.T) {
+	for format, path := range map[string]string{
+		"docker": imageTestDockerSave(t),
+		"oci":    imageTestOci(t),
+	} {
+		t.Run(format, func(t *testing.T) {
+			img, err := LoadContainerImage(path, "")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer img.Close()
+
+			assert.Equal(t, "/app", img.WorkingDir())
+			assert.Equal(t, []string{"dotnet", "MyApp.dll"}, img.Entrypoint())
+			assert.Equal(t, "Staging", img.Environment())
+
+			fsys := img.FS()
+			b, err := fs.ReadFile(fsys, "app/link.json")
+			assert.NoError(t, err)
+			assert.Contains(t, string(b), `"AppName": "base"`)
+
+			b, err = fs.ReadFile(fsys, "app/hard.json")
+			assert.NoError(t, err)
+			assert.Contains(t, string(b), `"Staging": true`)
+
+			// whiteouts
+			_, err = fs.Stat(fsys, "app/old.json")
+			assert.ErrorIs(t, err, fs.ErrNotExist)
+			names, err := fs.Glob(fsys, "etc/*/*")
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"etc/old/b.conf"}, names)
+			_, err = fs.Stat(fsys, "etc/keep.conf")
+			assert.NoError(t, err)
+
+			assert.NoError(t, fstest.TestFS(fsys, "app/appsettings.json", "app/appsettings.Staging.json", "etc/old/b.conf"))
+		})
+	}
+}
+
+func Test_ContainerImage_Builder(t *testing.T) {
+	img, err := LoadContainerImage(imageTestDockerSave(t), "myapp:1.0")
+	assert.NoError(t, err)
+	defer img.Close()
+
+	root, err := img.Builder().Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Debug", root.Get("Logging:Level"))
+	assert.Equal(t, "myapp:1.0 Env", root.GetEntry("Logging:Level").Source().Name())
+	assert.Equal(t, "base", root.Get("AppName"))
+	assert.Equal(t, "myapp:1.0:/app/appsettings.json", root.GetEntry("AppName").Source().Name())
+	assert.Equal(t, "True", root.Get("Staging"))
+	assert.Equal(t, "myapp:1.0:/app/appsettings.Staging.json", root.GetEntry("Staging").Source().Name())
+
+	// ASPNETCORE_ prefixed variables are available with and without prefix.
+	assert.Equal(t, "http://+:8080", root.Get("URLS"))
+	assert.Equal(t, "http://+:8080", root.Get("ASPNETCORE_URLS"))
+}
+
+func Test_ContainerImage_Errors(t *testing.T) {
+	_, err := LoadContainerImage(imageTestDockerSave(t), "other:1.0")
+	assert.Error(t, err)
+
+	notImage := filepath.Join(t.TempDir(), "not-image.tar")
+	assert.NoError(t, os.WriteFile(notImage, imageTestTar(t, []imageTestEntry{imageTestFile("a.txt", "a")}, false), 0600))
+	_, err = LoadContainerImage(notImage, "")
+	assert.Error(t, err)
+}
+
+func Test_ContainerImage_HardLinkSameLayer(t *testing.T) {
+	base := imageTestTar(t, []imageTestEntry{
+		imageTestFile("app/appsettings.json", `{"AppName": "base"}`),
+		{name: "app/hard.json", typeflag: tar.TypeLink, linkname: "app/appsettings.json"},
+		imageTestFile("etc/lower.conf", "lower"),
+	}, false)
+	top := imageTestTar(t, []imageTestEntry{
+		imageTestFile("app/appsettings.json", `{"AppName": "top"}`),
+		{name: "app/lower.conf", typeflag: tar.TypeLink, linkname: "etc/lower.conf"},
+	}, false)
+
+	img, err := LoadContainerImage(imageTestDockerSaveLayers(t, [][]byte{base, top}), "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer img.Close()
+
+	// The link keeps the content of the target in its own layer.
+	b, err := fs.ReadFile(img.FS(), "app/hard.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"AppName": "base"}`, string(b))
+	b, err = fs.ReadFile(img.FS(), "app/appsettings.json")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"AppName": "top"}`, string(b))
+
+	// The target in a lower layer is not part of the layer.
+	_, err = fs.ReadFile(img.FS(), "app/lower.conf")
+	assert.ErrorContains(t, err, "hard link target 'etc/lower.conf' not found")
+}
+
+func Test_ContainerImage_OciRef(t *testing.T) {
+	path := imageTestOciAnnotations(t, map[string]string{
+		"io.containerd.image.name":          "docker.io/library/myapp:1.0",
+		"org.opencontainers.image.ref.name": "1.0",
+	})
+	for _, ref := range []string{"docker.io/library/myapp:1.0", "1.0"} {
+		img, err := LoadContainerImage(path, ref)
+		if assert.NoError(t, err, ref) {
+			assert.Equal(t, "docker.io/library/myapp:1.0", img.Name())
+			_ = img.Close()
+		}
+	}
+
+	// Other repositories with the same tag are not the image.
+	_, err := LoadContainerImage(path, "docker.io/library/other:1.0")
+	assert.Error(t, err)
+
+	path = imageTestOciAnnotations(t, map[string]string{"org.opencontainers.image.ref.name": "1.0"})
+	_, err = LoadContainerImage(path, "other:1.0")
+	assert.Error(t, err)
+}
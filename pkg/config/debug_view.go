@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DebugViewContext is the information about a value passed to the callback
+// of [config.GetDebugView], same as ASP.NET ConfigurationDebugViewContext.
+type DebugViewContext struct {
+	// Path is the full key, e.g. "Logging:LogLevel:Default".
+	Path string
+	// Key is the last segment of the path, e.g. "Default".
+	Key string
+	// Value is the value of the key.
+	Value string
+	// Provider is the provider of the value, as it was in the snapshot which
+	// is rendered, see [config.RootConfig.Snapshot].
+	Provider Provider
+}
+
+// GetDebugView renders the configuration tree with the provider of every value
+// in exactly the same format as ASP.NET ConfigurationRootExtensions.GetDebugView:
+//
+//	Logging:
+//	  LogLevel:
+//	    Default=Information (appsettings.json)
+//
+// The provider is named after its source. The processValue callback, if not nil,
+// returns the value to render, e.g. to mask secrets. The view is rendered from
+// one snapshot of the configuration, so it is consistent during reloads.
+func GetDebugView(root RootConfig, processValue func(ctx DebugViewContext) string) string {
+	root = root.Snapshot()
+	providers := root.Providers()
+	sb := &strings.Builder{}
+
+	var recurseChildren func(parentPath string, indent string)
+	recurseChildren = func(parentPath string, indent string) {
+		for _, key := range root.GetChildKeys(parentPath) {
+			path := key
+			if parentPath != "" {
+				path = parentPath + keyDelimiter + key
+			}
+
+			if value, provider, found := getValueAndProvider(providers, path); found {
+				if processValue != nil {
+					value = processValue(DebugViewContext{Path: path, Key: key, Value: value, Provider: provider})
+				}
+
+				sb.WriteString(indent)
+				sb.WriteString(key)
+				sb.WriteString("=")
+				sb.WriteString(value)
+				sb.WriteString(" (")
+				sb.WriteString(providerName(provider))
+				sb.WriteString(")\n")
+			} else {
+				sb.WriteString(indent)
+				sb.WriteString(key)
+				sb.WriteString(":\n")
+			}
+
+			recurseChildren(path, indent+"  ")
+		}
+	}
+
+	recurseChildren("", "")
+	return sb.String()
+}
+
+// getValueAndProvider returns the value and the provider which provides it.
+func getValueAndProvider(providers []Provider, key string) (string, Provider, bool) {
+	for i := len(providers) - 1; i >= 0; i-- {
+		val := ""
+		if providers[i].TryGet(key, &val) {
+			return val, providers[i], true
+		}
+	}
+	return "", nil, false
+}
+
+func providerName(provider Provider) string {
+	return sourceName(provider.Source())
+}
+
+// ParseDebugView parses the output of [config.GetDebugView], or of ASP.NET
+// GetDebugView pasted e.g. into a support ticket, into [config.RootConfig].
+//
+// The entries have synthetic sources named after the providers in the text,
+// e.g. "JsonConfigurationProvider for 'appsettings.json' (Optional)". Values
+// spanning multiple lines are supported.
+func ParseDebugView(r io.Reader) (RootConfig, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var sources []*debugViewSource
+	sourcesByName := make(map[string]*debugViewSource)
+	addValue := func(providerName string, path string, value string) {
+		source, found := sourcesByName[providerName]
+		if !found {
+			source = &debugViewSource{name: providerName, data: make(map[string]string)}
+			sourcesByName[providerName] = source
+			sources = append(sources, source)
+		}
+		source.data[path] = value
+	}
+
+	var paths []string
+	lineNumber := 0
+
+	// The value which spans multiple lines.
+	pendingPath, pendingValue, pendingLine := "", "", 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if pendingPath != "" {
+			value, provider, found := splitDebugViewProvider(line)
+			if !found {
+				pendingValue += "\n" + line
+				continue
+			}
+			addValue(provider, pendingPath, pendingValue+"\n"+value)
+			pendingPath = ""
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		depth := indent / 2
+		if indent%2 != 0 || depth > len(paths) {
+			return nil, errors.Errorf("ParseDebugView: line %d: unexpected indentation", lineNumber)
+		}
+		paths = paths[:depth]
+
+		parentPath := ""
+		if depth > 0 {
+			parentPath = paths[depth-1] + keyDelimiter
+		}
+
+		if i := strings.Index(trimmed, "="); i > 0 {
+			path := parentPath + trimmed[:i]
+			paths = append(paths, path)
+
+			value, provider, found := splitDebugViewProvider(trimmed[i+1:])
+			if !found {
+				pendingPath, pendingValue, pendingLine = path, trimmed[i+1:], lineNumber
+				continue
+			}
+			addValue(provider, path, value)
+			continue
+		}
+
+		if strings.HasSuffix(trimmed, ":") && len(trimmed) > 1 {
+			paths = append(paths, parentPath+strings.TrimSuffix(trimmed, ":"))
+			continue
+		}
+
+		return nil, errors.Errorf("ParseDebugView: line %d: expected 'key=value (provider)' or 'key:'", lineNumber)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Errorf("ParseDebugView: %v", err)
+	}
+	if pendingPath != "" {
+		return nil, errors.Errorf("ParseDebugView: line %d: the value has no provider", pendingLine)
+	}
+
+	providers := make([]Provider, 0, len(sources))
+	for _, source := range sources {
+		providers = append(providers, newLoadedConfigProvider(newConfigImpl(source, source.data)))
+	}
+
+	root := newRootConfigImpl(func() ([]Provider, error) {
+		return providers, nil
+	})
+	root.setProviders(providers)
+	return root, nil
+}
+
+// splitDebugViewProvider splits "value (provider)" into the value and the
+// provider. The provider name can have balanced parentheses, e.g.
+// "JsonConfigurationProvider for 'appsettings.json' (Optional)".
+func splitDebugViewProvider(s string) (string, string, bool) {
+	if !strings.HasSuffix(s, ")") {
+		return "", "", false
+	}
+
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+		}
+
+		if depth == 0 {
+			if i == 0 || s[i-1] != ' ' {
+				return "", "", false
+			}
+			return s[:i-1], s[i+1 : len(s)-1], true
+		}
+	}
+	return "", "", false
+}
+
+// debugViewSource is the synthetic source of the values parsed by
+// [config.ParseDebugView], named after the provider in the text.
+type debugViewSource struct {
+	name string
+	data map[string]string
+}
+
+func (s *debugViewSource) Name() string {
+	return s.name
+}
+
+func (s *debugViewSource) Build() (Config, error) {
+	return newConfigImpl(s, s.data), nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDebugViewTestRoot(t *testing.T) RootConfig {
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{
+		"Logging": {"LogLevel": {"Default": "Information"}},
+		"AllowedHosts": "*",
+		"Hosts": ["a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"]
+	}`)).WithName("appsettings.json"))
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{
+		"Logging__LogLevel__Default": "Debug",
+		"Logging":                    "has value and children",
+		"ConnectionStrings__Sql":     "Server=x;Password=y",
+	}).WithName("EnvVarsSource"))
+
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	return root
+}
+
+const debugViewTestText = `AllowedHosts=* (appsettings.json)
+ConnectionStrings:
+  Sql=Server=x;Password=y (EnvVarsSource)
+Hosts:
+  0=a (appsettings.json)
+  1=b (appsettings.json)
+  2=c (appsettings.json)
+  3=d (appsettings.json)
+  4=e (appsettings.json)
+  5=f (appsettings.json)
+  6=g (appsettings.json)
+  7=h (appsettings.json)
+  8=i (appsettings.json)
+  9=j (appsettings.json)
+  10=k (appsettings.json)
+Logging=has value and children (EnvVarsSource)
+  LogLevel:
+    Default=Debug (EnvVarsSource)
+`
+
+func Test_GetDebugView(t *testing.T) {
+	root := newDebugViewTestRoot(t)
+	assert.Equal(t, debugViewTestText, GetDebugView(root, nil))
+
+	masked := GetDebugView(root, func(ctx DebugViewContext) string {
+		if ctx.Path == "ConnectionStrings:Sql" {
+			assert.Equal(t, "Sql", ctx.Key)
+			assert.Equal(t, "Server=x;Password=y", ctx.Value)
+			assert.Equal(t, "EnvVarsSource", ctx.Provider.Source().Name())
+			return "***"
+		}
+		return ctx.Value
+	})
+	assert.Contains(t, masked, "\n  Sql=*** (EnvVarsSource)\n")
+
+	// The view shows the values which the configuration serves.
+	providers := root.Providers()
+	providers[len(providers)-1].Set("Logging:LogLevel:Default", "set")
+	assert.Equal(t, "Debug", root.Get("Logging:LogLevel:Default"))
+	assert.Equal(t, debugViewTestText, GetDebugView(root, nil))
+}
+
+func Test_ParseDebugView_RoundTrip(t *testing.T) {
+	parsed, err := ParseDebugView(strings.NewReader(debugViewTestText))
+	assert.NoError(t, err)
+	assert.Equal(t, debugViewTestText, GetDebugView(parsed, nil))
+
+	entry := parsed.GetEntry("Logging:LogLevel:Default")
+	assert.Equal(t, "Debug", entry.Value())
+	assert.Equal(t, "EnvVarsSource", entry.Source().Name())
+	assert.Equal(t, "appsettings.json", parsed.GetEntry("Hosts:10").Source().Name())
+}
+
+func Test_ParseDebugView_AspNet(t *testing.T) {
+	text := "AllowedHosts=* (JsonConfigurationProvider for 'appsettings.json' (Optional))\r\n" +
+		"Certificate=-----BEGIN CERTIFICATE-----\r\n" +
+		"MIIB\r\n" +
+		"-----END CERTIFICATE----- (EnvironmentVariablesConfigurationProvider Prefix: '')\r\n" +
+		"Empty= (MemoryConfigurationProvider)\r\n" +
+		"Logging:\r\n" +
+		"  LogLevel:\r\n" +
+		"    Default=Information (JsonConfigurationProvider for 'appsettings.json' (Optional))\r\n" +
+		"    Microsoft.AspNetCore=Warning (JsonConfigurationProvider for 'appsettings.Development.json' (Optional))\r\n" +
+		"urls=http://localhost:5000 (EnvironmentVariablesConfigurationProvider Prefix: 'ASPNETCORE_')\r\n"
+
+	root, err := ParseDebugView(strings.NewReader(text))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "*", root.Get("AllowedHosts"))
+	assert.Equal(t, "JsonConfigurationProvider for 'appsettings.json' (Optional)", root.GetEntry("AllowedHosts").Source().Name())
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----", root.Get("Certificate"))
+	assert.Equal(t, "EnvironmentVariablesConfigurationProvider Prefix: ''", root.GetEntry("Certificate").Source().Name())
+	assert.Equal(t, "", root.Get("Empty"))
+	assert.Equal(t, "Warning", root.Get("Logging:LogLevel:Microsoft.AspNetCore"))
+	assert.Equal(t, "http://localhost:5000", root.Get("urls"))
+	assert.Len(t, root.Providers(), 5)
+}
+
+func Test_ParseDebugView_Errors(t *testing.T) {
+	for _, text := range []string{
+		" a=b (p)",
+		"a:\n    b=c (p)",
+		"not a key value",
+		"a=unterminated",
+	} {
+		_, err := ParseDebugView(strings.NewReader(text))
+		assert.Error(t, err, text)
+	}
+}
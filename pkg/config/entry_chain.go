@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EntryChain is the value of a key in every layer of [config.RootConfig] which
+// sets it, in precedence order, see [config.RootConfig.GetEntryChain].
+type EntryChain struct {
+	// Key is the key with the casing of the first layer which sets it.
+	Key string
+	// Layers are the entries of the key, the later layers take precedence
+	// over earlier ones. The last one is the winner.
+	Layers []EntryLayer
+}
+
+// EntryLayer is the value of a key in one layer of [config.RootConfig].
+type EntryLayer struct {
+	Entry
+	// Winner reports whether this value is the effective value of the key.
+	Winner bool
+}
+
+// Winner returns the entry with the effective value, or nil when no layer
+// sets the key.
+func (c EntryChain) Winner() Entry {
+	for _, layer := range c.Layers {
+		if layer.Winner {
+			return layer.Entry
+		}
+	}
+	return nil
+}
+
+// String renders the chain for reports, e.g.
+// "appsettings.json: Info -> appsettings.Production.json: Warning -> env: Debug (wins)".
+func (c EntryChain) String() string {
+	parts := make([]string, 0, len(c.Layers))
+	for _, layer := range c.Layers {
+		part := fmt.Sprintf("%s: %s", sourceName(layer.Source()), layer.Value())
+		if layer.Winner {
+			part += " (wins)"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+func (c *rootConfigImpl) GetEntryChain(key string) EntryChain {
+	return c.getSnapshot().getEntryChain(c.queryKey(key))
+}
+
+func (c *rootConfigImpl) GetEntriesWithHistory() []EntryChain {
+	snapshot := c.getSnapshot()
+	chains := make([]EntryChain, 0, len(snapshot.keys))
+	for _, key := range snapshot.keys {
+		chains = append(chains, snapshot.getEntryChain(key))
+	}
+	return chains
+}
+
+// getEntryChain returns the chain from the same layers which provide the
+// effective values, so the winner is always what Get returns.
+func (s *rootSnapshot) getEntryChain(key string) EntryChain {
+	if entry, found := s.entries[normalizeKey(key)]; found {
+		key = entry.Key()
+	}
+
+	chain := EntryChain{Key: key}
+	for _, layer := range s.layers {
+		if entry, found := layer.tryGetEntry(key); found {
+			chain.Layers = append(chain.Layers, EntryLayer{Entry: entry})
+		}
+	}
+
+	markWinner(chain.Layers)
+	return chain
+}
+
+func markWinner(layers []EntryLayer) {
+	if len(layers) > 0 {
+		layers[len(layers)-1].Winner = true
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RootConfig_GetEntryChain(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"Logging": {"Level": "Info"}, "Name": "app"}`)).WithName("appsettings.json"))
+	builder.AddSource(NewJsonSource([]byte(`{"Logging": {"Level": "Warning"}}`)).WithName("appsettings.Production.json"))
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{"Logging__Level": "Debug"}).WithName("env"))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	chain := root.GetEntryChain("LOGGING:LEVEL")
+	assert.Equal(t, "Logging:Level", chain.Key)
+	if assert.Len(t, chain.Layers, 3) {
+		assert.Equal(t, "Info", chain.Layers[0].Value())
+		assert.False(t, chain.Layers[0].Winner)
+		assert.Equal(t, "appsettings.Production.json", chain.Layers[1].Source().Name())
+		assert.True(t, chain.Layers[2].Winner)
+	}
+	assert.Equal(t, "Debug", chain.Winner().Value())
+	assert.Equal(t, "appsettings.json: Info -> appsettings.Production.json: Warning -> env: Debug (wins)", chain.String())
+
+	missing := root.GetEntryChain("missing")
+	assert.Empty(t, missing.Layers)
+	assert.Nil(t, missing.Winner())
+
+	chains := root.GetEntriesWithHistory()
+	if assert.Len(t, chains, 2) {
+		assert.Equal(t, chain.String(), chains[0].String())
+		assert.Equal(t, "Name", chains[1].Key)
+		assert.Equal(t, "appsettings.json: app (wins)", chains[1].String())
+	}
+
+	// The chains are built from the same values as Get, so the values set on
+	// the providers directly are not reported.
+	root.Providers()[2].Set("Name", "set")
+	assert.Equal(t, "app", root.Get("Name"))
+	assert.Equal(t, "appsettings.json: app (wins)", root.GetEntryChain("name").String())
+	assert.Equal(t, "appsettings.json: app (wins)", root.GetEntriesWithHistory()[1].String())
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnvDumpFormat is the format of environment listing, see [config.ParseEnvDump].
+type EnvDumpFormat string
+
+// Formats of environment listings recognised by [config.ParseEnvDump].
+const (
+	// EnvDumpPrintenv is output of "printenv" or "env".
+	EnvDumpPrintenv EnvDumpFormat = "printenv"
+	// EnvDumpNullDelimited is output of "env -0".
+	EnvDumpNullDelimited EnvDumpFormat = "env -0"
+	// EnvDumpWindowsSet is output of Windows "set" command.
+	EnvDumpWindowsSet EnvDumpFormat = "windows set"
+	// EnvDumpPowerShellTable is output of "Get-ChildItem env: | Format-Table".
+	EnvDumpPowerShellTable EnvDumpFormat = "powershell table"
+	// EnvDumpKubectl is output of "kubectl exec -- env".
+	EnvDumpKubectl EnvDumpFormat = "kubectl exec env"
+)
+
+var (
+	// envDumpVarRegex matches the start of a variable in printenv output.
+	// Anything else is a continuation of the multi-line value of the previous variable.
+	envDumpVarRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.:\-]*=`)
+
+	envDumpPowerShellHeaderRegex = regexp.MustCompile(`^Name\s+Value\s*$`)
+	envDumpPowerShellDashesRegex = regexp.MustCompile(`^(-+\s+)-+\s*$`)
+)
+
+// NewEnvDumpSource creates [config.EnvVarsSource] from environment listing,
+// e.g. sent by a customer in a support ticket. The name is the name of the
+// source, e.g. the name of the file with the listing. See [config.ParseEnvDump]
+// for supported formats.
+func NewEnvDumpSource(name string, prefix string, dump []byte) (*EnvVarsSource, error) {
+	m, _, err := ParseEnvDump(dump)
+	if err != nil {
+		return nil, newSourceError("EnvDumpSource", name, "", err)
+	}
+	return NewEnvVarsMapSource(prefix, m).WithName(name), nil
+}
+
+// ParseEnvDump parses environment listing and returns the variables and the
+// detected format. These formats are recognised:
+//
+//   - printenv or env output, and kubectl exec -- env. Lines which do not look
+//     like a start of a new variable are continuation of multi-line values.
+//   - env -0 output, which keeps multi-line values exactly.
+//   - Windows set output.
+//   - PowerShell Get-ChildItem env: | Format-Table output. Long values wrapped
+//     with -Wrap are joined back, values truncated by PowerShell cannot be recovered.
+//
+// When a variable is listed more than once, the last value wins.
+func ParseEnvDump(dump []byte) (map[string]string, EnvDumpFormat, error) {
+	text := string(dump)
+	if strings.TrimSpace(text) == "" {
+		return nil, "", errors.New("environment listing is empty")
+	}
+
+	if strings.Contains(text, "\x00") {
+		return parseEnvDumpNullDelimited(text), EnvDumpNullDelimited, nil
+	}
+
+	lines := strings.Split(strings.TrimRight(text, "\r\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	if m, found := parseEnvDumpPowerShellTable(lines); found {
+		return m, EnvDumpPowerShellTable, nil
+	}
+
+	if isEnvDumpWindows(lines) {
+		return parseEnvDumpWindowsSet(lines), EnvDumpWindowsSet, nil
+	}
+
+	m, err := parseEnvDumpPrintenv(lines)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, found := m["KUBERNETES_SERVICE_HOST"]; found {
+		return m, EnvDumpKubectl, nil
+	}
+	return m, EnvDumpPrintenv, nil
+}
+
+func parseEnvDumpNullDelimited(text string) map[string]string {
+	m := make(map[string]string)
+	for _, record := range strings.Split(text, "\x00") {
+		fields := strings.SplitN(record, "=", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+		m[strings.TrimLeft(fields[0], "\r\n")] = fields[1]
+	}
+	return m
+}
+
+func parseEnvDumpPrintenv(lines []string) (map[string]string, error) {
+	m := make(map[string]string)
+	current := ""
+	for _, line := range lines {
+		if envDumpVarRegex.MatchString(line) {
+			fields := strings.SplitN(line, "=", 2)
+			current = fields[0]
+			m[current] = fields[1]
+			continue
+		}
+
+		// Skip anything before the first variable, e.g. messages from kubectl.
+		if current != "" {
+			m[current] = fmt.Sprintf("%s\n%s", m[current], line)
+		}
+	}
+
+	if len(m) == 0 {
+		return nil, errors.New("no environment variables found in the listing")
+	}
+	return m, nil
+}
+
+// isEnvDumpWindows detects Windows set output by variables which are always
+// set on Windows. Line endings do not tell the format, as the listings of
+// Linux containers are often saved on Windows with CRLF line endings.
+func isEnvDumpWindows(lines []string) bool {
+	for _, line := range lines {
+		upper := strings.ToUpper(line)
+		if strings.HasPrefix(upper, "WINDIR=") || strings.HasPrefix(upper, "SYSTEMROOT=") || strings.HasPrefix(line, "=") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEnvDumpWindowsSet parses Windows set output. Names can have characters
+// like "(" in "ProgramFiles(x86)", and hidden variables like "=C:" are skipped.
+// Values cannot span multiple lines on Windows.
+func parseEnvDumpWindowsSet(lines []string) map[string]string {
+	m := make(map[string]string)
+	for _, line := range lines {
+		fields := strings.SplitN(line, "=", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+		m[fields[0]] = fields[1]
+	}
+	return m
+}
+
+// parseEnvDumpPowerShellTable parses Format-Table output which looks like:
+//
+//	Name                           Value
+//	----                           -----
+//	ALLUSERSPROFILE                C:\ProgramData
+//
+// The value column starts where the second group of dashes starts.
+func parseEnvDumpPowerShellTable(lines []string) (map[string]string, bool) {
+	start := -1
+	for i := 0; i+1 < len(lines); i++ {
+		if envDumpPowerShellHeaderRegex.MatchString(strings.TrimSpace(lines[i])) &&
+			envDumpPowerShellDashesRegex.MatchString(strings.TrimSpace(lines[i+1])) {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+
+	dashes := lines[start]
+	indent := len([]rune(dashes)) - len([]rune(strings.TrimLeft(dashes, " ")))
+	valueColumn := indent + len([]rune(envDumpPowerShellDashesRegex.FindStringSubmatch(strings.TrimSpace(dashes))[1]))
+
+	m := make(map[string]string)
+	current := ""
+	for _, line := range lines[start+1:] {
+		runes := []rune(line)
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		name, value := string(runes), ""
+		if len(runes) > valueColumn {
+			name, value = string(runes[:valueColumn]), string(runes[valueColumn:])
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimRight(value, " ")
+
+		if name == "" {
+			if current != "" {
+				m[current] += strings.TrimLeft(value, " ")
+			}
+			continue
+		}
+
+		current = name
+		m[current] = value
+	}
+
+	return m, true
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseEnvDump_Printenv(t *testing.T) {
+	dump := "HOME=/root\n" +
+		"ConnectionStrings__Sql=Server=db;Database=app\n" +
+		"CERT=-----BEGIN CERTIFICATE-----\n" +
+		"MIIB\n" +
+		"\n" +
+		"-----END CERTIFICATE-----\n" +
+		"Logging:Level=Debug\n"
+
+	m, format, err := ParseEnvDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvDumpPrintenv, format)
+	assert.Equal(t, map[string]string{
+		"HOME":                   "/root",
+		"ConnectionStrings__Sql": "Server=db;Database=app",
+		"CERT":                   "-----BEGIN CERTIFICATE-----\nMIIB\n\n-----END CERTIFICATE-----",
+		"Logging:Level":          "Debug",
+	}, m)
+}
+
+func Test_ParseEnvDump_PrintenvCRLF(t *testing.T) {
+	dump := "HOME=/root\r\n" +
+		"CERT=-----BEGIN CERTIFICATE-----\r\n" +
+		"MIIB\r\n" +
+		"-----END CERTIFICATE-----\r\n" +
+		"Logging__Level=Debug\r\n"
+
+	m, format, err := ParseEnvDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvDumpPrintenv, format)
+	assert.Equal(t, map[string]string{
+		"HOME":           "/root",
+		"CERT":           "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----",
+		"Logging__Level": "Debug",
+	}, m)
+}
+
+func Test_ParseEnvDump_NullDelimited(t *testing.T) {
+	dump := "A=1\x00MULTI=line 1\nline 2\nNOT_A_VAR=x\x00B=\x00"
+
+	m, format, err := ParseEnvDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvDumpNullDelimited, format)
+	assert.Equal(t, map[string]string{
+		"A":     "1",
+		"MULTI": "line 1\nline 2\nNOT_A_VAR=x",
+		"B":     "",
+	}, m)
+}
+
+func Test_ParseEnvDump_WindowsSet(t *testing.T) {
+	dump := "=C:=C:\\app\r\n" +
+		"ALLUSERSPROFILE=C:\\ProgramData\r\n" +
+		"ProgramFiles(x86)=C:\\Program Files (x86)\r\n" +
+		"windir=C:\\Windows\r\n"
+
+	m, format, err := ParseEnvDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvDumpWindowsSet, format)
+	assert.Equal(t, map[string]string{
+		"ALLUSERSPROFILE":   "C:\\ProgramData",
+		"ProgramFiles(x86)": "C:\\Program Files (x86)",
+		"windir":            "C:\\Windows",
+	}, m)
+}
+
+func Test_ParseEnvDump_PowerShellTable(t *testing.T) {
+	dump := "\r\n" +
+		"Name                           Value\r\n" +
+		"----                           -----\r\n" +
+		"ALLUSERSPROFILE                C:\\ProgramData\r\n" +
+		"ConnectionStrings__Sql         Server=db;Database=app;User Id=sa;Password=very-long-\r\n" +
+		"                               password\r\n" +
+		"EMPTY\r\n" +
+		"Logging__Level                 Debug\r\n" +
+		"\r\n"
+
+	m, format, err := ParseEnvDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvDumpPowerShellTable, format)
+	assert.Equal(t, map[string]string{
+		"ALLUSERSPROFILE":        "C:\\ProgramData",
+		"ConnectionStrings__Sql": "Server=db;Database=app;User Id=sa;Password=very-long-password",
+		"EMPTY":                  "",
+		"Logging__Level":         "Debug",
+	}, m)
+}
+
+func Test_ParseEnvDump_Kubectl(t *testing.T) {
+	dump := "PATH=/usr/local/sbin:/usr/local/bin\n" +
+		"HOSTNAME=myapp-7d9f8\n" +
+		"ASPNETCORE_ENVIRONMENT=Production\n" +
+		"KUBERNETES_SERVICE_HOST=10.0.0.1\n"
+
+	m, format, err := ParseEnvDump([]byte(dump))
+	assert.NoError(t, err)
+	assert.Equal(t, EnvDumpKubectl, format)
+	assert.Equal(t, "Production", m["ASPNETCORE_ENVIRONMENT"])
+}
+
+func Test_ParseEnvDump_Invalid(t *testing.T) {
+	_, _, err := ParseEnvDump([]byte("  \n"))
+	assert.Error(t, err)
+
+	_, _, err = ParseEnvDump([]byte("no variables here\n"))
+	assert.Error(t, err)
+}
+
+func Test_NewEnvDumpSource(t *testing.T) {
+	source, err := NewEnvDumpSource("ticket-123/env.txt", "", []byte("Logging__Level=Debug\nAppName=app\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ticket-123/env.txt", source.Name())
+
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"Logging": {"Level": "Info"}}`)))
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	entry := root.GetEntry("Logging:Level")
+	assert.Equal(t, "Debug", entry.Value())
+	assert.Equal(t, "ticket-123/env.txt", entry.Source().Name())
+}
@@ -12,9 +12,12 @@ import (
 //
 // The tests were ported from ASP.NET too, so the behaviour is identical.
 //
-// See: https://github.com/dotnet/runtime/blob/release/6.0/src/libraries/Microsoft.Extensions.Configuration.EnvironmentVariables/src/EnvironmentVariablesConfigurationProvider.cs
+// See: https://github.com/dotnet/runtime/blob/release/7.0/src/libraries/Microsoft.Extensions.Configuration.EnvironmentVariables/src/EnvironmentVariablesConfigurationProvider.cs
 type envVarsLoader struct {
 	prefix string
+
+	// locations are the original names of the variables by normalised keys.
+	locations map[string]Location
 }
 
 // newEnvVarsLoader creates a loader of config from env variables.
@@ -22,7 +25,8 @@ func newEnvVarsLoader(prefix string) *envVarsLoader {
 	e := &envVarsLoader{
 		// Don't use normalize, the "__" should not be replaced in prefix, just lower case.
 		// This is how ASP.NET behaves, there is even a test for this.
-		prefix: strings.ToLower(prefix),
+		prefix:    strings.ToLower(prefix),
+		locations: make(map[string]Location),
 	}
 
 	return e
@@ -41,51 +45,57 @@ func (e *envVarsLoader) Load(envVars map[string]string) map[string]string {
 	m := make(map[string]string, len(envVars))
 
 	// NOTE: the weird logic around SQL is taken as-is from ASP.NET codebase.
-	for k, v := range envVars {
+	// The keys keep the casing of the variables, the prefixes are case-insensitive.
+	for name, v := range envVars {
 		prefix := ""
 		provider := ""
 
-		k = normalizeKey(k)
+		k := envKeyName(name)
 
-		if strings.HasPrefix(k, envMySqlServerPrefix) {
+		if hasPrefixFold(k, envMySqlServerPrefix) {
 			prefix = envMySqlServerPrefix
 			provider = "MySql.Data.MySqlClient"
-		} else if strings.HasPrefix(k, envSqlAzureServerPrefix) {
+		} else if hasPrefixFold(k, envSqlAzureServerPrefix) {
 			prefix = envSqlAzureServerPrefix
 			provider = "System.Data.SqlClient"
-		} else if strings.HasPrefix(k, envSqlServerPrefix) {
+		} else if hasPrefixFold(k, envSqlServerPrefix) {
 			prefix = envSqlServerPrefix
 			provider = "System.Data.SqlClient"
-		} else if strings.HasPrefix(k, envCustomPrefix) {
+		} else if hasPrefixFold(k, envCustomPrefix) {
 			prefix = envCustomPrefix
 		} else {
-			e.addIfPrefixed(m, k, v)
+			e.addIfPrefixed(m, k, v, name)
 			continue
 		}
 
 		k = e.trimPrefix(k, prefix)
-		k2 := normalizeKey(fmt.Sprintf("ConnectionStrings:%s", k))
-		e.addIfPrefixed(m, k2, v)
+		k2 := fmt.Sprintf("ConnectionStrings:%s", k)
+		e.addIfPrefixed(m, k2, v, name)
 
 		if provider != "" {
-			k3 := normalizeKey(fmt.Sprintf("ConnectionStrings:%s_ProviderName", k))
-			e.addIfPrefixed(m, k3, provider)
+			k3 := fmt.Sprintf("ConnectionStrings:%s_ProviderName", k)
+			e.addIfPrefixed(m, k3, provider, name)
 		}
 	}
 
 	return m
 }
 
-func (e *envVarsLoader) addIfPrefixed(m map[string]string, key string, val string) {
-	if strings.HasPrefix(key, e.prefix) {
-		key = strings.TrimPrefix(key, e.prefix)
-		key = strings.TrimPrefix(key, keyDelimiter)
+func (e *envVarsLoader) addIfPrefixed(m map[string]string, key string, val string, name string) {
+	if hasPrefixFold(key, e.prefix) {
+		key = e.trimPrefix(key, e.prefix)
 		m[key] = val
+		e.locations[normalizeKey(key)] = Location{Variable: name}
 	}
 }
 
 func (e *envVarsLoader) trimPrefix(key string, prefix string) string {
-	key = strings.TrimPrefix(key, prefix)
+	key = key[len(prefix):]
 	key = strings.TrimPrefix(key, keyDelimiter)
 	return key
 }
+
+// hasPrefixFold is case-insensitive strings.HasPrefix.
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
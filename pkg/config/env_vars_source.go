@@ -50,6 +50,7 @@ func (s *EnvVarsSource) Name() string {
 }
 
 func (s *EnvVarsSource) Build() (Config, error) {
-	m := newEnvVarsLoader(s.prefix).Load(s.m)
-	return newConfigImpl(s, m), nil
+	loader := newEnvVarsLoader(s.prefix)
+	m := loader.Load(s.m)
+	return newLocatedConfig(newConfigImpl(s, m), loader.locations), nil
 }
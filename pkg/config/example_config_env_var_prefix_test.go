@@ -36,6 +36,6 @@ func Example_envVarsPrefix() {
 	}
 
 	// Output:
-	// key=setting_a, value=A
-	// key=setting_b, value=B
+	// key=SETTING_A, value=A
+	// key=SETTING_B, value=B
 }
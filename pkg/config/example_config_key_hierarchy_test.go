@@ -51,6 +51,6 @@ func Example_keyHierarchy() {
 	}
 
 	// Output:
-	// key=myapp:logging:enabled, value=true, source=json file
-	// key=myapp:logging:level, value=debug, source=env vars
+	// key=MyApp:Logging:Enabled, value=True, source=json file
+	// key=MyApp:Logging:Level, value=debug, source=env vars
 }
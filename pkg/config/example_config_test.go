@@ -58,23 +58,23 @@ func Example() {
 	// Output:
 	// KEYS - sorted alphabetically:
 	//   - bar
-	//   - connectionstrings:blob
-	//   - connectionstrings:redis
-	//   - connectionstrings:sql
+	//   - CONNECTIONSTRINGS:BLOB
+	//   - ConnectionStrings:Redis
+	//   - ConnectionStrings:Sql
 	//   - foo
 	//   - zoo
 	// ENTRIES - sorted by key:
 	//   - key=bar, value=bar from json, provider=json provider
-	//   - key=connectionstrings:blob, value=blob from env, provider=env provider
-	//   - key=connectionstrings:redis, value=redis from json, provider=json provider
-	//   - key=connectionstrings:sql, value=sql from env, provider=env provider
+	//   - key=CONNECTIONSTRINGS:BLOB, value=blob from env, provider=env provider
+	//   - key=ConnectionStrings:Redis, value=redis from json, provider=json provider
+	//   - key=ConnectionStrings:Sql, value=sql from env, provider=env provider
 	//   - key=foo, value=foo from env, provider=env provider
 	//   - key=zoo, value=zoo from env, provider=env provider
 	// KEY VALUES:
 	//   - key=bar, value=bar from json
-	//   - key=connectionstrings:blob, value=blob from env
-	//   - key=connectionstrings:redis, value=redis from json
-	//   - key=connectionstrings:sql, value=sql from env
+	//   - key=CONNECTIONSTRINGS:BLOB, value=blob from env
+	//   - key=ConnectionStrings:Redis, value=redis from json
+	//   - key=ConnectionStrings:Sql, value=sql from env
 	//   - key=foo, value=foo from env
 	//   - key=zoo, value=zoo from env
 
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// NewFileSource creates configuration source for a file which implements
+// [config.Source]. The format is picked by the file extension from registered
+// formats, see [config.RegisterFormat], unless it is set with WithFormat.
+// The file is read when the source is built, from the file system set with
+// WithFileSystem, or from the OS.
+//
+// When built by [config.Builder], the file system and the base path, which
+// relative paths are relative to, default to [config.PropertyFileSystem] and
+// [config.PropertyBasePath] if the builder has these properties.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{
+		path: path,
+	}
+}
+
+// NewJsonFileSource creates configuration source for Json file, same as
+// NewFileSource with WithFileSystem and the Json format. The name of the
+// source is the path of the file, unless the file system provides a better
+// name, like [config.GitFS] does.
+func NewJsonFileSource(fsys fs.FS, path string) *FileSource {
+	return NewFileSource(path).WithFileSystem(fsys).WithFormat(FormatJson)
+}
+
+// FileSource implements [config.Source] interface.
+type FileSource struct {
+	fsys   fs.FS
+	path   string
+	name   string
+	format string
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *FileSource) WithName(name string) *FileSource {
+	s.name = name
+	return s
+}
+
+// WithFileSystem sets the file system which the file is read from, e.g.
+// os.DirFS or [config.GitFS], and returns itself. The file system set
+// explicitly is not replaced by the builder properties.
+func (s *FileSource) WithFileSystem(fsys fs.FS) *FileSource {
+	s.fsys = fsys
+	return s
+}
+
+// WithFormat sets the name of the format of the file, e.g. for files without
+// extension, and returns itself.
+func (s *FileSource) WithFormat(format string) *FileSource {
+	s.format = format
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *FileSource) Name() string {
+	switch {
+	case s.name != "":
+		return s.name
+	case s.fsys != nil:
+		return fsDisplayName(s.fsys, s.path)
+	default:
+		return s.path
+	}
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *FileSource) Build() (Config, error) {
+	return s.BuildWithDefaults(nil)
+}
+
+// BuildWithDefaults builds Config, taking the file system and the base path
+// from the builder properties, unless the file system is set explicitly.
+// Part of [config.DefaultsSource] interface.
+func (s *FileSource) BuildWithDefaults(properties map[string]interface{}) (Config, error) {
+	config, err := s.load(properties)
+	if err != nil {
+		return nil, newSourceError("FileSource", s.Name(), s.path, err)
+	}
+	return config, nil
+}
+
+// load parses the file into Config where the entries implement
+// [config.LocatedEntry]. Only Json values have line and column, the values in
+// other formats have just the file.
+func (s *FileSource) load(properties map[string]interface{}) (Config, error) {
+	format := s.format
+	var parser FormatParser
+	if format != "" {
+		p, found := LookupFormat(format)
+		if !found {
+			return nil, errors.Errorf("unknown format '%s'", format)
+		}
+		parser = p
+	} else {
+		name, p, found := LookupFormatByPath(s.path)
+		if !found {
+			return nil, errors.Errorf("no format is registered for the extension of '%s'", s.path)
+		}
+		format, parser = name, p
+	}
+
+	defaultFS, _ := properties[PropertyFileSystem].(fs.FS)
+	basePath, _ := properties[PropertyBasePath].(string)
+
+	path := s.path
+	var b []byte
+	var err error
+	switch {
+	case s.fsys != nil:
+		b, err = fs.ReadFile(s.fsys, path)
+	case defaultFS != nil:
+		if path, err = fsPath(path, basePath); err == nil {
+			b, err = fs.ReadFile(defaultFS, path)
+		}
+	case basePath != "" && !filepath.IsAbs(path):
+		path = filepath.Join(basePath, path)
+		b, err = os.ReadFile(path)
+	default:
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(format, FormatJson) {
+		loader := newJsonFileLoader(path)
+		if _, err := loader.Load(bytes.NewReader(b)); err != nil {
+			return nil, err
+		}
+		return loader.config(s), nil
+	}
+
+	m, err := parser(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make(map[string]Location, len(m))
+	for key := range m {
+		locations[normalizeKey(key)] = Location{File: path}
+	}
+	return newLocatedConfig(newConfigImpl(s, m), locations), nil
+}
+
+// fsPath returns the path of the file inside the builder file system. The
+// absolute paths are made relative to the base path, which is the root of the
+// file system, like in ASP.NET.
+func fsPath(path string, basePath string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(filepath.Clean(path)), nil
+	}
+	if basePath == "" {
+		return "", errors.Errorf("absolute path '%s' cannot be read from the builder file system without base path", path)
+	}
+
+	rel, err := filepath.Rel(basePath, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("absolute path '%s' is outside of base path '%s'", path, basePath)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// NewStreamSource creates configuration source which reads the configuration
+// in the format from the reader, e.g. stdin. The reader is read in full the
+// first time the source is built, later builds use the same content.
+func NewStreamSource(r io.Reader, format string) *StreamSource {
+	return &StreamSource{
+		r:      r,
+		format: format,
+		name:   fmt.Sprintf("StreamSource (%s)", format),
+	}
+}
+
+// StreamSource implements [config.Source] interface.
+type StreamSource struct {
+	r      io.Reader
+	format string
+	name   string
+
+	once    sync.Once
+	data    []byte
+	readErr error
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *StreamSource) WithName(name string) *StreamSource {
+	s.name = name
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *StreamSource) Name() string {
+	return s.name
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *StreamSource) Build() (Config, error) {
+	parser, found := LookupFormat(s.format)
+	if !found {
+		return nil, newSourceError("StreamSource", s.name, "", errors.Errorf("unknown format '%s'", s.format))
+	}
+
+	s.once.Do(func() {
+		s.data, s.readErr = io.ReadAll(s.r)
+	})
+	if s.readErr != nil {
+		return nil, newSourceError("StreamSource", s.name, "", s.readErr)
+	}
+
+	m, err := parser(bytes.NewReader(s.data))
+	if err != nil {
+		return nil, newSourceError("StreamSource", s.name, "", err)
+	}
+	return newConfigImpl(s, m), nil
+}
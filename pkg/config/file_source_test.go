@@ -0,0 +1,149 @@
+package config
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var registerTestFormatOnce sync.Once
+
+// registerTestFormat registers "kv" format of "key=value" lines, as third
+// parties would do.
+func registerTestFormat(t *testing.T) {
+	registerTestFormatOnce.Do(func() {
+		err := RegisterFormat("KV", []string{"kv", ".properties"}, func(r io.Reader) (map[string]string, error) {
+			m := make(map[string]string)
+			scanner := bufio.NewScanner(r)
+			for scanner.Scan() {
+				if fields := strings.SplitN(scanner.Text(), "=", 2); len(fields) == 2 {
+					m[fields[0]] = fields[1]
+				}
+			}
+			return m, scanner.Err()
+		})
+		assert.NoError(t, err)
+	})
+}
+
+func Test_FileSource_ByExtension(t *testing.T) {
+	registerTestFormat(t)
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "appsettings.JSON")
+	kvPath := filepath.Join(dir, "app.kv")
+	assert.NoError(t, os.WriteFile(jsonPath, []byte(`{"Logging": {"Level": "Info"}}`), 0600))
+	assert.NoError(t, os.WriteFile(kvPath, []byte("Logging:Level=Debug\n"), 0600))
+
+	config, err := NewFileSource(jsonPath).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "Info", config.Get("Logging:Level"))
+	assert.Equal(t, jsonPath, config.Source().Name())
+
+	config, err = NewFileSource(kvPath).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "Debug", config.Get("Logging:Level"))
+
+	_, err = NewFileSource(filepath.Join(dir, "app.unknown")).Build()
+	assert.Error(t, err)
+
+	_, err = NewFileSource(filepath.Join(dir, "missing.json")).Build()
+	assert.Error(t, err)
+}
+
+func Test_FileSource_WithFormat(t *testing.T) {
+	registerTestFormat(t)
+	fsys := fstest.MapFS{
+		"config": {Data: []byte("a=1\n")},
+	}
+
+	config, err := NewFileSource("config").WithFileSystem(fsys).WithFormat("kv").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", config.Get("A"))
+
+	_, err = NewFileSource("config").WithFileSystem(fsys).WithFormat("nope").Build()
+	assert.Error(t, err)
+}
+
+func Test_FileSource_SharedBetweenBuilders(t *testing.T) {
+	source := NewFileSource("appsettings.json")
+
+	first := NewBuilder()
+	first.Properties()[PropertyFileSystem] = fstest.MapFS{"appsettings.json": {Data: []byte(`{"a": "first"}`)}}
+	first.AddSource(source)
+
+	second := NewBuilder()
+	second.Properties()[PropertyFileSystem] = fstest.MapFS{"appsettings.json": {Data: []byte(`{"a": "second"}`)}}
+	second.AddSource(source)
+
+	// Each builder builds the source with its own properties.
+	firstRoot, err := first.Build()
+	assert.NoError(t, err)
+	secondRoot, err := second.Build()
+	assert.NoError(t, err)
+
+	assert.NoError(t, firstRoot.Reload())
+	assert.Equal(t, "first", firstRoot.Get("a"))
+	assert.Equal(t, "second", secondRoot.Get("a"))
+	assert.Same(t, source, firstRoot.Providers()[0].Source())
+}
+
+func Test_FileSource_AbsolutePathInFileSystem(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "app")
+	builder := NewBuilder()
+	builder.Properties()[PropertyFileSystem] = fstest.MapFS{"config/appsettings.json": {Data: []byte(`{"a": "fs"}`)}}
+	builder.Properties()[PropertyBasePath] = base
+	builder.AddSource(NewFileSource(filepath.Join(base, "config", "appsettings.json")))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "fs", root.Get("a"))
+
+	builder.AddSource(NewFileSource(filepath.Join(filepath.Dir(base), "other.json")))
+	_, err = builder.Build()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is outside of base path")
+
+	delete(builder.Properties(), PropertyBasePath)
+	_, err = builder.Build()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be read from the builder file system without base path")
+}
+
+func Test_StreamSource(t *testing.T) {
+	source := NewStreamSource(strings.NewReader(`{"a": {"b": "c"}}`), FormatJson)
+	assert.Equal(t, "StreamSource (json)", source.Name())
+
+	for i := 0; i < 2; i++ {
+		config, err := source.Build()
+		assert.NoError(t, err)
+		assert.Equal(t, "c", config.Get("a:b"))
+	}
+
+	_, err := NewStreamSource(strings.NewReader(""), "nope").Build()
+	assert.Error(t, err)
+}
+
+func Test_FormatRegistry(t *testing.T) {
+	registerTestFormat(t)
+	assert.Contains(t, Formats(), "json")
+	assert.Contains(t, Formats(), "kv")
+
+	name, _, found := LookupFormatByPath("a/b/app.PROPERTIES")
+	assert.True(t, found)
+	assert.Equal(t, "kv", name)
+
+	parser := func(r io.Reader) (map[string]string, error) { return nil, nil }
+	registry := newFormatRegistryImpl()
+	assert.NoError(t, registry.register("yaml", []string{".yaml", ".yml"}, parser))
+	assert.Error(t, registry.register("YAML", nil, parser))
+	assert.Error(t, registry.register("other", []string{".YML"}, parser))
+	assert.Error(t, registry.register("", nil, parser))
+	assert.Error(t, registry.register("nil", nil, nil))
+	assert.Equal(t, []string{"yaml"}, registry.names())
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+
+	"github.com/pkg/errors"
+)
+
+// fsDisplayNamer is implemented by file systems which can give a more
+// descriptive name for a file than its path, e.g. [config.GitFS] adds the
+// revision. The name is used as the name of file-based sources.
+type fsDisplayNamer interface {
+	displayName(path string) string
+}
+
+// fsDisplayName returns the name of the file for the use in source names.
+func fsDisplayName(fsys fs.FS, path string) string {
+	if namer, ok := fsys.(fsDisplayNamer); ok {
+		return namer.displayName(path)
+	}
+	return path
+}
+
+// memFile implements [fs.File] interface for file content held in memory.
+type memFile struct {
+	info fs.FileInfo
+	r    *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Read(b []byte) (int, error) { return f.r.Read(b) }
+func (f *memFile) Close() error               { return nil }
+
+// Seek implements [io.Seeker] interface.
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	return f.r.Seek(offset, whence)
+}
+
+// memDir implements [fs.ReadDirFile] interface for directory listing held in memory.
+type memDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read(_ []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *memDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	remaining := len(d.entries) - d.offset
+	if count > 0 && remaining == 0 {
+		return nil, io.EOF
+	}
+	if count <= 0 || count > remaining {
+		count = remaining
+	}
+
+	entries := d.entries[d.offset : d.offset+count]
+	d.offset += count
+	return entries, nil
+}
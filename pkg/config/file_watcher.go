@@ -0,0 +1,228 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFileWatcherInterval = 2 * time.Second
+	defaultFileWatcherDebounce = 500 * time.Millisecond
+)
+
+// Clock provides the current time and timers, so tests can use a fake clock.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time
+	// on the returned channel, same as time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock implements [config.Clock] with the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// NewFileWatcher creates a watcher which polls the files for changes. It does not
+// rely on file system notifications, which are unreliable inside containers.
+//
+// A file is changed when its modification time, size or the hash of its
+// content changes, when it is created or deleted, or when the symbolic links
+// leading to it point somewhere else. The latter detects Kubernetes ConfigMap
+// and Secret volume updates, which atomically swap the "..data" symbolic link
+// instead of modifying the files.
+//
+// The state of the files is recorded when the watcher is created.
+func NewFileWatcher(paths ...string) *FileWatcher {
+	w := &FileWatcher{
+		paths:    paths,
+		interval: defaultFileWatcherInterval,
+		debounce: defaultFileWatcherDebounce,
+		clock:    systemClock{},
+	}
+	w.states = w.readStates()
+	return w
+}
+
+// FileWatcher polls files for changes, see [config.NewFileWatcher].
+type FileWatcher struct {
+	paths    []string
+	interval time.Duration
+	debounce time.Duration
+	clock    Clock
+
+	mu         sync.Mutex
+	states     map[string]fileState
+	pending    map[string]bool
+	lastChange time.Time
+}
+
+// fileState is the observed state of a watched file.
+type fileState struct {
+	exists   bool
+	realPath string
+	modTime  time.Time
+	size     int64
+	hash     [sha256.Size]byte
+}
+
+// WithInterval sets how often the files are polled and returns itself.
+func (w *FileWatcher) WithInterval(interval time.Duration) *FileWatcher {
+	w.interval = interval
+	return w
+}
+
+// WithDebounce sets for how long the files must stay unchanged after a change
+// before it is reported, so bursts of changes are reported once, and returns itself.
+func (w *FileWatcher) WithDebounce(debounce time.Duration) *FileWatcher {
+	w.debounce = debounce
+	return w
+}
+
+// WithClock sets the clock used for polling and debouncing and returns itself.
+func (w *FileWatcher) WithClock(clock Clock) *FileWatcher {
+	w.clock = clock
+	return w
+}
+
+// Watch polls the files until the context is done, and invokes onChange with
+// the sorted paths of changed files once the changes settle. Watch returns
+// the error of the context.
+func (w *FileWatcher) Watch(ctx context.Context, onChange func(changed []string)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.clock.After(w.interval):
+		}
+
+		if changed := w.poll(); len(changed) > 0 {
+			onChange(changed)
+		}
+	}
+}
+
+// poll checks the files once and returns the changed files when the changes
+// have settled for the debounce duration.
+func (w *FileWatcher) poll() []string {
+	states := w.readStates()
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for path, state := range states {
+		if state != w.states[path] {
+			if w.pending == nil {
+				w.pending = make(map[string]bool)
+			}
+			w.pending[path] = true
+			w.lastChange = now
+		}
+	}
+	w.states = states
+
+	if len(w.pending) == 0 || now.Sub(w.lastChange) < w.debounce {
+		return nil
+	}
+
+	changed := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		changed = append(changed, path)
+	}
+	sort.Strings(changed)
+	w.pending = nil
+	return changed
+}
+
+func (w *FileWatcher) readStates() map[string]fileState {
+	states := make(map[string]fileState, len(w.paths))
+	for _, path := range w.paths {
+		states[path] = readFileState(path)
+	}
+	return states
+}
+
+func readFileState(path string) fileState {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	info, err := os.Stat(realPath)
+	if err != nil || info.IsDir() {
+		return fileState{}
+	}
+
+	b, err := os.ReadFile(realPath)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{
+		exists:   true,
+		realPath: realPath,
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+		hash:     sha256.Sum256(b),
+	}
+}
+
+// ReloadOnChange watches the files with the watcher until the context is done,
+// and reloads the provider of the source in the root when the files change.
+// The source may be wrapped in the root, e.g. by [config.NewOptionalSource] or
+// by [config.BuildOptions.LegacyKeyNormalization]. Load errors are passed to
+// onError, if not nil, and the provider keeps the previous values.
+// ReloadOnChange returns an error if no provider of the root is built from the
+// source, otherwise the error of the context.
+//
+// The root reports the changes with [config.RootConfig.OnChange].
+func ReloadOnChange(ctx context.Context, root RootConfig, source Source, watcher *FileWatcher, onError func(err error)) error {
+	if len(sourceProviders(root, source)) == 0 {
+		return errors.Errorf("ReloadOnChange: no provider is built from source %s", source.Name())
+	}
+
+	return watcher.Watch(ctx, func(changed []string) {
+		for _, provider := range sourceProviders(root, source) {
+			if err := provider.Load(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	})
+}
+
+// sourceProviders returns the providers of the root built from the source,
+// either directly or through wrapper sources.
+func sourceProviders(root RootConfig, source Source) []Provider {
+	var providers []Provider
+	for _, provider := range root.Providers() {
+		if isWrappedSource(provider.Source(), source) {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// isWrappedSource reports whether the source is the wrapped source, or wraps
+// it with [config.OptionalSource] or [config.LegacyKeySource].
+func isWrappedSource(source Source, wrapped Source) bool {
+	for source != wrapped {
+		switch s := source.(type) {
+		case *OptionalSource:
+			source = s.Source()
+		case *LegacyKeySource:
+			source = s.Source()
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock implements [config.Clock], the time moves only with Advance.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeClockWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	var waiters []fakeClockWaiter
+	for _, w := range c.waiters {
+		if c.now.Before(w.deadline) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+// waitForWaiters waits until somebody waits on After.
+func (c *fakeClock) waitForWaiters(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		c.mu.Lock()
+		n := len(c.waiters)
+		c.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("nobody waits for the clock")
+}
+
+func Test_FileWatcher_Debounce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"a": 1}`), 0600))
+
+	clock := newFakeClock()
+	watcher := NewFileWatcher(path).WithClock(clock).WithDebounce(time.Second)
+	assert.Empty(t, watcher.poll())
+
+	// Same size and likely same modification time, detected by the hash.
+	assert.NoError(t, os.WriteFile(path, []byte(`{"a": 2}`), 0600))
+	assert.Empty(t, watcher.poll())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(path, []byte(`{"a": 3}`), 0600))
+	assert.Empty(t, watcher.poll())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Empty(t, watcher.poll())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, []string{path}, watcher.poll())
+	assert.Empty(t, watcher.poll())
+}
+
+func Test_FileWatcher_CreateAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "appsettings.json")
+	other := filepath.Join(dir, "other.json")
+	assert.NoError(t, os.WriteFile(other, []byte(`{}`), 0600))
+
+	watcher := NewFileWatcher(path, other).WithClock(newFakeClock()).WithDebounce(0)
+	assert.NoError(t, os.WriteFile(path, []byte(`{}`), 0600))
+	assert.NoError(t, os.Remove(other))
+	assert.Equal(t, []string{path, other}, watcher.poll())
+}
+
+func Test_FileWatcher_KubernetesSymlinkSwap(t *testing.T) {
+	// The layout of ConfigMap volume:
+	//	appsettings.json -> ..data/appsettings.json
+	//	..data -> ..2024_01_01_1
+	//	..2024_01_01_1/appsettings.json
+	dir := t.TempDir()
+	for _, version := range []string{"..2024_01_01_1", "..2024_01_01_2"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, version), 0700))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, version, "appsettings.json"), []byte(`{"a": 1}`), 0600))
+	}
+	assert.NoError(t, os.Symlink("..2024_01_01_1", filepath.Join(dir, "..data")))
+	path := filepath.Join(dir, "appsettings.json")
+	assert.NoError(t, os.Symlink(filepath.Join("..data", "appsettings.json"), path))
+
+	watcher := NewFileWatcher(path).WithClock(newFakeClock()).WithDebounce(0)
+	assert.Empty(t, watcher.poll())
+
+	// Atomic swap, the content is identical.
+	assert.NoError(t, os.Symlink("..2024_01_01_2", filepath.Join(dir, "..data_tmp")))
+	assert.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	assert.Equal(t, []string{path}, watcher.poll())
+	assert.Empty(t, watcher.poll())
+}
+
+func Test_ReloadOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"Level": "Info"}`), 0600))
+
+	source := NewFileSource(path)
+	builder := NewBuilder()
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	changes := make(chan []ConfigChange, 1)
+	root.OnChange(func(c []ConfigChange) { changes <- c })
+
+	clock := newFakeClock()
+	watcher := NewFileWatcher(path).WithClock(clock).WithInterval(time.Second).WithDebounce(0)
+	errs := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ReloadOnChange(ctx, root, source, watcher, func(err error) { errs <- err })
+	}()
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"Level": "Debug"}`), 0600))
+	clock.waitForWaiters(t)
+	clock.Advance(time.Second)
+
+	select {
+	case c := <-changes:
+		if assert.Len(t, c, 1) {
+			assert.Equal(t, "Debug", c[0].New.Value())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	assert.Equal(t, "Debug", root.Get("Level"))
+
+	// Broken file keeps the previous values.
+	assert.NoError(t, os.WriteFile(path, []byte(`{"Level": `), 0600))
+	clock.waitForWaiters(t)
+	clock.Advance(time.Second)
+
+	select {
+	case err := <-errs:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+	assert.Equal(t, "Debug", root.Get("Level"))
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func Test_ReloadOnChange_WrappedSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+
+	source := NewFileSource(path)
+	builder := NewBuilder()
+	builder.AddSource(NewOptionalSource(source))
+	root, err := builder.BuildWithOptions(BuildOptions{LegacyKeyNormalization: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "", root.Get("Level"))
+
+	changes := make(chan []ConfigChange, 1)
+	root.OnChange(func(c []ConfigChange) { changes <- c })
+
+	clock := newFakeClock()
+	watcher := NewFileWatcher(path).WithClock(clock).WithInterval(time.Second).WithDebounce(0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- ReloadOnChange(ctx, root, source, watcher, nil)
+	}()
+
+	clock.waitForWaiters(t)
+	assert.NoError(t, os.WriteFile(path, []byte(`{"Level": "Debug"}`), 0600))
+	clock.Advance(time.Second)
+
+	select {
+	case c := <-changes:
+		if assert.Len(t, c, 1) {
+			assert.Equal(t, "Debug", c[0].New.Value())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	assert.Equal(t, "Debug", root.Get("Level"))
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func Test_ReloadOnChange_UnknownSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appsettings.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"Level": "Info"}`), 0600))
+
+	builder := NewBuilder()
+	builder.AddSource(NewFileSource(path))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	watcher := NewFileWatcher(path).WithClock(newFakeClock())
+	err = ReloadOnChange(context.Background(), root, NewFileSource(path), watcher, nil)
+	assert.Error(t, err)
+}
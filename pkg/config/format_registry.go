@@ -0,0 +1,138 @@
+package config
+
+import (
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// FormatParser parses configuration file into a flat map of keys and values,
+// e.g. Json {"Logging": {"Level": "Debug"}} becomes "Logging:Level": "Debug".
+// The keys do not need to be normalised.
+type FormatParser func(r io.Reader) (map[string]string, error)
+
+// FormatJson is the name of the built-in Json format, used for ".json" files.
+const FormatJson = "json"
+
+// formatRegistry holds registered formats, see [config.RegisterFormat].
+var formatRegistry = newFormatRegistryImpl()
+
+func init() {
+	err := RegisterFormat(FormatJson, []string{".json"}, func(r io.Reader) (map[string]string, error) {
+		return newJsonLoader().Load(r)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegisterFormat registers the parser of a configuration format, so it can be
+// used by [config.NewFileSource] and [config.NewStreamSource]. The name and
+// extensions, e.g. "yaml" and [".yaml", ".yml"], are case-insensitive. It is an
+// error to register a name or an extension which is already registered.
+//
+// This is safe to call concurrently, normally from init function of the
+// package which implements the format.
+func RegisterFormat(name string, extensions []string, parser FormatParser) error {
+	return formatRegistry.register(name, extensions, parser)
+}
+
+// Formats returns the names of registered formats, sorted.
+func Formats() []string {
+	return formatRegistry.names()
+}
+
+// LookupFormat returns the parser of the format with the name.
+func LookupFormat(name string) (FormatParser, bool) {
+	return formatRegistry.lookup(name)
+}
+
+// LookupFormatByPath returns the name and the parser of the format for the file
+// extension of the path, e.g. "json" for "appsettings.json".
+func LookupFormatByPath(path string) (string, FormatParser, bool) {
+	return formatRegistry.lookupByPath(path)
+}
+
+// formatRegistryImpl is a concurrency-safe registry of formats.
+type formatRegistryImpl struct {
+	mu         sync.RWMutex
+	parsers    map[string]FormatParser
+	extensions map[string]string
+}
+
+func newFormatRegistryImpl() *formatRegistryImpl {
+	return &formatRegistryImpl{
+		parsers:    make(map[string]FormatParser),
+		extensions: make(map[string]string),
+	}
+}
+
+func (r *formatRegistryImpl) register(name string, extensions []string, parser FormatParser) error {
+	name = strings.ToLower(name)
+	if name == "" {
+		return errors.New("format name is empty")
+	}
+	if parser == nil {
+		return errors.Errorf("format '%s': parser is nil", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, found := r.parsers[name]; found {
+		return errors.Errorf("format '%s' is already registered", name)
+	}
+
+	normalized := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if other, found := r.extensions[ext]; found {
+			return errors.Errorf("format '%s': extension '%s' is already registered by format '%s'", name, ext, other)
+		}
+		normalized = append(normalized, ext)
+	}
+
+	r.parsers[name] = parser
+	for _, ext := range normalized {
+		r.extensions[ext] = name
+	}
+	return nil
+}
+
+func (r *formatRegistryImpl) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.parsers))
+	for name := range r.parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *formatRegistryImpl) lookup(name string) (FormatParser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	parser, found := r.parsers[strings.ToLower(name)]
+	return parser, found
+}
+
+func (r *formatRegistryImpl) lookupByPath(path string) (string, FormatParser, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, found := r.extensions[strings.ToLower(filepath.Ext(path))]
+	if !found {
+		return "", nil, false
+	}
+	return name, r.parsers[name], true
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"github.com/pkg/errors"
+)
+
+// NewFunctionsLocalSettingsSource creates configuration source for Azure Functions
+// local.settings.json which implements [config.Source].
+//
+// The file looks like this:
+//
+//	{
+//		"IsEncrypted": false,
+//		"Values": {
+//			"FUNCTIONS_WORKER_RUNTIME": "dotnet-isolated",
+//			"MyOptions__Level": "Debug"
+//		},
+//		"ConnectionStrings": {
+//			"Sql": "<some value>"
+//		}
+//	}
+//
+// The Functions host injects "Values" as environmental variables, so they are
+// loaded in the same way as [config.EnvVarsSource] does, e.g. both "a__b" and
+// "a:b" become "a:b". The "ConnectionStrings" are available under
+// "ConnectionStrings:<name>" keys, and take precedence over "Values".
+// The "Host" section is not part of the app configuration and is ignored.
+func NewFunctionsLocalSettingsSource(json []byte) *FunctionsLocalSettingsSource {
+	return &FunctionsLocalSettingsSource{
+		json: json,
+		name: "FunctionsLocalSettingsSource",
+	}
+}
+
+// NewFunctionsLocalSettingsFileSource is same as NewFunctionsLocalSettingsSource
+// except the file is read from the file system when the source is built.
+func NewFunctionsLocalSettingsFileSource(fsys fs.FS, path string) *FunctionsLocalSettingsSource {
+	return &FunctionsLocalSettingsSource{
+		fsys: fsys,
+		path: path,
+		name: fsDisplayName(fsys, path),
+	}
+}
+
+// FunctionsLocalSettingsSource implements [config.Source] interface.
+type FunctionsLocalSettingsSource struct {
+	json      []byte
+	fsys      fs.FS
+	path      string
+	name      string
+	decryptor func(value string) (string, error)
+}
+
+// functionsLocalSettings is the schema of local.settings.json.
+type functionsLocalSettings struct {
+	IsEncrypted       bool                       `json:"IsEncrypted"`
+	Values            map[string]interface{}     `json:"Values"`
+	ConnectionStrings map[string]json.RawMessage `json:"ConnectionStrings"`
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *FunctionsLocalSettingsSource) WithName(name string) *FunctionsLocalSettingsSource {
+	s.name = name
+	return s
+}
+
+// WithValueDecryptor sets the function which decrypts values of encrypted
+// file ("IsEncrypted": true) and returns itself. Azure Functions Core Tools
+// encrypt the values with a machine-specific key, so there is no built-in
+// decryptor. Without it, encrypted files are refused with an error.
+func (s *FunctionsLocalSettingsSource) WithValueDecryptor(decryptor func(value string) (string, error)) *FunctionsLocalSettingsSource {
+	s.decryptor = decryptor
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *FunctionsLocalSettingsSource) Name() string {
+	return s.name
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *FunctionsLocalSettingsSource) Build() (Config, error) {
+	m, err := s.load()
+	if err != nil {
+		return nil, newSourceError("FunctionsLocalSettingsSource", s.name, s.path, err)
+	}
+	return newConfigImpl(s, m), nil
+}
+
+func (s *FunctionsLocalSettingsSource) load() (map[string]string, error) {
+	data := s.json
+	if s.fsys != nil {
+		b, err := fs.ReadFile(s.fsys, s.path)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+
+	b, err := readJsonc(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var settings functionsLocalSettings
+	dec := json.NewDecoder(b)
+	dec.UseNumber()
+	if err := dec.Decode(&settings); err != nil {
+		return nil, err
+	}
+
+	if settings.IsEncrypted && s.decryptor == nil {
+		return nil, errors.New("the file is encrypted (IsEncrypted: true) and no decryptor is supplied, " +
+			"decrypt it with 'func settings decrypt' or use WithValueDecryptor")
+	}
+
+	values := make(map[string]string, len(settings.Values))
+	for k, v := range settings.Values {
+		value, err := functionsSettingValue(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Values: '%s'", k)
+		}
+		if values[k], err = s.decrypt(settings.IsEncrypted, value); err != nil {
+			return nil, errors.Wrapf(err, "Values: '%s'", k)
+		}
+	}
+
+	m := newEnvVarsLoader("").Load(values)
+	names := make(foldedKeys, len(m))
+	for key := range m {
+		names[normalizeKey(key)] = key
+	}
+
+	for name, raw := range settings.ConnectionStrings {
+		connectionString, providerName, err := functionsConnectionString(raw)
+		if err != nil {
+			return nil, errors.Wrapf(err, "ConnectionStrings: '%s'", name)
+		}
+		if connectionString, err = s.decrypt(settings.IsEncrypted, connectionString); err != nil {
+			return nil, errors.Wrapf(err, "ConnectionStrings: '%s'", name)
+		}
+
+		names.set(m, fmt.Sprintf("ConnectionStrings:%s", name), connectionString)
+		if providerName != "" {
+			names.set(m, fmt.Sprintf("ConnectionStrings:%s_ProviderName", name), providerName)
+		}
+	}
+
+	return m, nil
+}
+
+func (s *FunctionsLocalSettingsSource) decrypt(isEncrypted bool, value string) (string, error) {
+	if !isEncrypted || value == "" {
+		return value, nil
+	}
+	return s.decryptor(value)
+}
+
+// functionsSettingValue converts the value to string in the same way
+// the Core Tools do, e.g. true becomes "True".
+func functionsSettingValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case bool:
+		if val {
+			return "True", nil
+		}
+		return "False", nil
+	default:
+		return "", errors.New("value must be a string")
+	}
+}
+
+// functionsConnectionString reads the connection string which is either a
+// string, or an object with "ConnectionString" and "ProviderName".
+func functionsConnectionString(raw json.RawMessage) (string, string, error) {
+	var connectionString string
+	if err := json.Unmarshal(raw, &connectionString); err == nil {
+		return connectionString, "", nil
+	}
+
+	var obj struct {
+		ConnectionString string `json:"ConnectionString"`
+		ProviderName     string `json:"ProviderName"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return "", "", errors.New("connection string must be a string or an object with ConnectionString")
+	}
+	return obj.ConnectionString, obj.ProviderName, nil
+}
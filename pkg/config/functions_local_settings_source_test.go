@@ -0,0 +1,97 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const functionsLocalSettingsJson = `
+{
+	// comments are allowed
+	"IsEncrypted": false,
+	"Values": {
+		"AzureWebJobsStorage": "UseDevelopmentStorage=true",
+		"FUNCTIONS_WORKER_RUNTIME": "dotnet-isolated",
+		"MyOptions__Level": "Debug",
+		"MyOptions:Name": "name",
+		"Enabled": true,
+		"Port": 7071,
+		"SQLCONNSTR_Legacy": "legacy"
+	},
+	"Host": {
+		"LocalHttpPort": 7071
+	},
+	"ConnectionStrings": {
+		"Sql": "Server=localhost",
+		"Other": {"ConnectionString": "Server=other", "ProviderName": "System.Data.SqlClient"}
+	}
+}
+`
+
+func Test_FunctionsLocalSettingsSource(t *testing.T) {
+	config, err := NewFunctionsLocalSettingsSource([]byte(functionsLocalSettingsJson)).Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "UseDevelopmentStorage=true", config.Get("AzureWebJobsStorage"))
+	assert.Equal(t, "Debug", config.Get("MyOptions:Level"))
+	assert.Equal(t, "name", config.Get("MyOptions:Name"))
+	assert.Equal(t, "True", config.Get("Enabled"))
+	assert.Equal(t, "7071", config.Get("Port"))
+	assert.Equal(t, "", config.Get("Host:LocalHttpPort"))
+
+	// Values behave like env vars, including special prefixes.
+	assert.Equal(t, "legacy", config.Get("ConnectionStrings:Legacy"))
+	assert.Equal(t, "System.Data.SqlClient", config.Get("ConnectionStrings:Legacy_ProviderName"))
+
+	assert.Equal(t, "Server=localhost", config.Get("ConnectionStrings:Sql"))
+	assert.Equal(t, "Server=other", config.Get("ConnectionStrings:Other"))
+	assert.Equal(t, "System.Data.SqlClient", config.Get("ConnectionStrings:Other_ProviderName"))
+}
+
+func Test_FunctionsLocalSettingsSource_File(t *testing.T) {
+	fsys := fstest.MapFS{
+		"src/local.settings.json": {Data: []byte(functionsLocalSettingsJson)},
+	}
+
+	source := NewFunctionsLocalSettingsFileSource(fsys, "src/local.settings.json")
+	assert.Equal(t, "src/local.settings.json", source.Name())
+
+	config, err := source.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "Debug", config.Get("MyOptions:Level"))
+
+	_, err = NewFunctionsLocalSettingsFileSource(fsys, "missing.json").Build()
+	assert.Error(t, err)
+}
+
+func Test_FunctionsLocalSettingsSource_Encrypted(t *testing.T) {
+	json := `{
+		"IsEncrypted": true,
+		"Values": {"Secret": "ZW5jcnlwdGVk"},
+		"ConnectionStrings": {"Sql": "ZW5jcnlwdGVkIHNxbA=="}
+	}`
+
+	_, err := NewFunctionsLocalSettingsSource([]byte(json)).Build()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "IsEncrypted")
+	}
+
+	decryptor := func(value string) (string, error) {
+		return "decrypted " + strings.ToLower(value), nil
+	}
+	config, err := NewFunctionsLocalSettingsSource([]byte(json)).WithValueDecryptor(decryptor).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "decrypted zw5jcnlwdgvk", config.Get("Secret"))
+	assert.Equal(t, "decrypted zw5jcnlwdgvkihnxba==", config.Get("ConnectionStrings:Sql"))
+}
+
+func Test_FunctionsLocalSettingsSource_InvalidValues(t *testing.T) {
+	_, err := NewFunctionsLocalSettingsSource([]byte(`{"Values": {"a": {"b": "c"}}}`)).Build()
+	assert.Error(t, err)
+
+	_, err = NewFunctionsLocalSettingsSource([]byte(`{"ConnectionStrings": {"a": 1}}`)).Build()
+	assert.Error(t, err)
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// NewGitFS creates [config.GitFS] for the revision (commit, branch or tag) of
+// the git repository in repoDir. The revision is resolved to a commit once, so
+// the file system does not change even if the branch moves on.
+//
+// The "git" command must be on the PATH.
+func NewGitFS(repoDir string, revision string) (*GitFS, error) {
+	if strings.HasPrefix(revision, "-") {
+		return nil, errors.Errorf("GitFS: invalid revision '%s'", revision)
+	}
+
+	g := &GitFS{
+		repoDir:  repoDir,
+		revision: revision,
+	}
+
+	commit, err := g.git("rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	if err != nil {
+		return nil, errors.Errorf("GitFS: unknown revision '%s': %v", revision, err)
+	}
+	g.commit = strings.TrimSpace(string(commit))
+
+	short, err := g.git("rev-parse", "--short", g.commit)
+	if err != nil {
+		return nil, errors.Errorf("GitFS: %v", err)
+	}
+	g.shortCommit = strings.TrimSpace(string(short))
+
+	commitTime, err := g.git("show", "-s", "--format=%ct", g.commit)
+	if err != nil {
+		return nil, errors.Errorf("GitFS: %v", err)
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(commitTime)), 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("GitFS: invalid commit time: %v", err)
+	}
+	g.modTime = time.Unix(seconds, 0)
+
+	return g, nil
+}
+
+// GitFS is a read-only [fs.FS] with the files as they exist at a git revision,
+// without checking it out. The files are read with "git ls-tree" and
+// "git cat-file". The paths are relative to the root of the repository.
+//
+// File-based sources, e.g. [config.NewJsonFileSource], include the revision in
+// their names, e.g. "appsettings.json@a1b2c3d", so the effective configuration
+// can be compared between revisions.
+type GitFS struct {
+	repoDir     string
+	revision    string
+	commit      string
+	shortCommit string
+	modTime     time.Time
+}
+
+// Revision returns the revision as given to [config.NewGitFS].
+func (g *GitFS) Revision() string {
+	return g.revision
+}
+
+// Commit returns the full hash of the commit the revision was resolved to.
+func (g *GitFS) Commit() string {
+	return g.commit
+}
+
+// Open opens the named file. Part of [fs.FS] interface.
+func (g *GitFS) Open(name string) (fs.File, error) {
+	info, err := g.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		entries, err := g.readDir("open", name, info.object)
+		if err != nil {
+			return nil, err
+		}
+		return &memDir{info: info, entries: entries}, nil
+	}
+
+	data, err := g.readBlob("open", name, info.object)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{info: info, r: bytes.NewReader(data)}, nil
+}
+
+// ReadFile reads the named file. Part of [fs.ReadFileFS] interface.
+func (g *GitFS) ReadFile(name string) ([]byte, error) {
+	info, err := g.stat("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	return g.readBlob("read", name, info.object)
+}
+
+// ReadDir reads the named directory. Part of [fs.ReadDirFS] interface.
+func (g *GitFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	info, err := g.stat("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return g.readDir("readdir", name, info.object)
+}
+
+// Stat returns the info of the named file. Part of [fs.StatFS] interface.
+func (g *GitFS) Stat(name string) (fs.FileInfo, error) {
+	return g.stat("stat", name)
+}
+
+func (g *GitFS) displayName(path string) string {
+	return fmt.Sprintf("%s@%s", path, g.shortCommit)
+}
+
+func (g *GitFS) stat(op string, name string) (*gitFileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if name == "." {
+		return &gitFileInfo{name: ".", mode: fs.ModeDir | 0555, modTime: g.modTime, object: g.commit + "^{tree}"}, nil
+	}
+
+	out, err := g.git("ls-tree", "-z", "-l", "--full-tree", g.commit, "--", name)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	infos := g.parseLsTree(out)
+	if len(infos) != 1 {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return infos[0], nil
+}
+
+func (g *GitFS) readDir(op string, name string, object string) ([]fs.DirEntry, error) {
+	out, err := g.git("ls-tree", "-z", "-l", object)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	infos := g.parseLsTree(out)
+	entries := make([]fs.DirEntry, 0, len(infos))
+	for _, info := range infos {
+		entries = append(entries, info)
+	}
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (g *GitFS) readBlob(op string, name string, object string) ([]byte, error) {
+	data, err := g.git("cat-file", "blob", object)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// parseLsTree parses output of "git ls-tree -z -l", which has records like
+// "<mode> <type> <object> <size>\t<path>\0". Submodules are skipped.
+func (g *GitFS) parseLsTree(out []byte) []*gitFileInfo {
+	var infos []*gitFileInfo
+	for _, record := range strings.Split(string(out), "\x00") {
+		tab := strings.IndexByte(record, '\t')
+		if tab < 0 {
+			continue
+		}
+
+		fields := strings.Fields(record[:tab])
+		if len(fields) != 4 {
+			continue
+		}
+
+		info := &gitFileInfo{
+			name:    path.Base(record[tab+1:]),
+			modTime: g.modTime,
+			object:  fields[2],
+		}
+
+		switch fields[1] {
+		case "tree":
+			info.mode = fs.ModeDir | 0555
+		case "blob":
+			info.mode = 0444
+			if fields[0] == "100755" {
+				info.mode = 0555
+			}
+			info.size, _ = strconv.ParseInt(fields[3], 10, 64)
+		default:
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func (g *GitFS) git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.repoDir}, args...)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// gitFileInfo implements [fs.FileInfo] and [fs.DirEntry] interfaces.
+type gitFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	object  string
+}
+
+func (i *gitFileInfo) Name() string               { return i.name }
+func (i *gitFileInfo) Size() int64                { return i.size }
+func (i *gitFileInfo) Mode() fs.FileMode          { return i.mode }
+func (i *gitFileInfo) ModTime() time.Time         { return i.modTime }
+func (i *gitFileInfo) IsDir() bool                { return i.mode.IsDir() }
+func (i *gitFileInfo) Sys() interface{}           { return nil }
+func (i *gitFileInfo) Type() fs.FileMode          { return i.mode.Type() }
+func (i *gitFileInfo) Info() (fs.FileInfo, error) { return i, nil }
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gitTestRepo creates a git repository with two commits of appsettings files.
+func gitTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		assert.NoErrorf(t, err, "git %v: %s", args, out)
+	}
+	write := func(name string, content string) {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	run("init", "-q")
+	write("appsettings.json", `{"Logging": {"Level": "Info"}, "AppName": "app"}`)
+	write("config/appsettings.Production.json", `{"Logging": {"Level": "Warning"}}`)
+	run("add", "-A")
+	run("commit", "-q", "-m", "first")
+	run("tag", "v1")
+
+	write("appsettings.json", `{"Logging": {"Level": "Debug"}, "AppName": "app"}`)
+	run("commit", "-q", "-am", "second")
+
+	// Uncommitted changes are not visible.
+	write("appsettings.json", `{"Logging": {"Level": "Trace"}}`)
+	return dir
+}
+
+func Test_GitFS_ReadsRevision(t *testing.T) {
+	dir := gitTestRepo(t)
+
+	v1, err := NewGitFS(dir, "v1")
+	assert.NoError(t, err)
+	head, err := NewGitFS(dir, "HEAD")
+	assert.NoError(t, err)
+	assert.NotEqual(t, v1.Commit(), head.Commit())
+
+	build := func(fsys fs.FS) RootConfig {
+		builder := NewBuilder()
+		builder.AddSource(NewJsonFileSource(fsys, "appsettings.json"))
+		root, err := builder.Build()
+		assert.NoError(t, err)
+		return root
+	}
+
+	rootV1 := build(v1)
+	rootHead := build(head)
+	assert.Equal(t, "Info", rootV1.Get("Logging:Level"))
+	assert.Equal(t, "Debug", rootHead.Get("Logging:Level"))
+
+	sourceName := rootV1.GetEntry("AppName").Source().Name()
+	assert.True(t, strings.HasPrefix(sourceName, "appsettings.json@"), sourceName)
+	assert.True(t, strings.HasPrefix(v1.Commit(), strings.TrimPrefix(sourceName, "appsettings.json@")), sourceName)
+
+	matches, err := fs.Glob(v1, "config/appsettings*.json")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"config/appsettings.Production.json"}, matches)
+
+	_, err = v1.Open("missing.json")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func Test_GitFS_Conformance(t *testing.T) {
+	dir := gitTestRepo(t)
+
+	fsys, err := NewGitFS(dir, "v1")
+	assert.NoError(t, err)
+	assert.NoError(t, fstest.TestFS(fsys, "appsettings.json", "config/appsettings.Production.json"))
+}
+
+func Test_GitFS_UnknownRevision(t *testing.T) {
+	dir := gitTestRepo(t)
+
+	_, err := NewGitFS(dir, "no-such-branch")
+	assert.Error(t, err)
+
+	_, err = NewGitFS(dir, "--help")
+	assert.Error(t, err)
+}
@@ -0,0 +1,383 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults for [config.HttpJsonSource] polling.
+const (
+	defaultHttpJsonPollInterval = 30 * time.Second
+	defaultHttpJsonMaxBackoff   = 5 * time.Minute
+)
+
+// NewHttpJsonSource creates configuration source for Json document published
+// at the URL. The document is parsed in the same way as [config.JsonSource].
+//
+// The source remembers the ETag of the last document and sends If-None-Match,
+// so unchanged documents are not downloaded again. When a cache file is set,
+// the last good document is persisted there and used when the URL is not
+// available. Values from the cache are flagged as stale, see [config.HttpJsonEntry].
+func NewHttpJsonSource(url string) *HttpJsonSource {
+	return &HttpJsonSource{
+		name:         fmt.Sprintf("HttpJsonSource: %s", url),
+		url:          url,
+		httpClient:   http.DefaultClient,
+		pollInterval: defaultHttpJsonPollInterval,
+		maxBackoff:   defaultHttpJsonMaxBackoff,
+		clock:        systemClock{},
+	}
+}
+
+// HttpJsonSource implements [config.Source] interface.
+type HttpJsonSource struct {
+	name         string
+	url          string
+	httpClient   *http.Client
+	cacheFile    string
+	pollInterval time.Duration
+	maxBackoff   time.Duration
+	errorHandler func(err error)
+	clock        Clock
+
+	mu   sync.Mutex
+	last *httpJsonDocument
+	// cacheMu serialises writing the cache file.
+	cacheMu sync.Mutex
+}
+
+// httpJsonDocument is the last good document, also the format of the cache file.
+type httpJsonDocument struct {
+	Url       string    `json:"url"`
+	ETag      string    `json:"etag"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Document  string    `json:"document"`
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *HttpJsonSource) WithName(name string) *HttpJsonSource {
+	s.name = name
+	return s
+}
+
+// WithHttpClient sets the http.Client used to send requests and returns itself.
+func (s *HttpJsonSource) WithHttpClient(httpClient *http.Client) *HttpJsonSource {
+	s.httpClient = httpClient
+	return s
+}
+
+// WithCacheFile sets the file where the last good document is persisted and returns itself.
+func (s *HttpJsonSource) WithCacheFile(path string) *HttpJsonSource {
+	s.cacheFile = path
+	return s
+}
+
+// WithPollInterval sets the interval between polls, and the maximum backoff
+// on failures, and returns itself. See [config.HttpJsonSource.Poll].
+func (s *HttpJsonSource) WithPollInterval(interval time.Duration, maxBackoff time.Duration) *HttpJsonSource {
+	s.pollInterval = interval
+	s.maxBackoff = maxBackoff
+	return s
+}
+
+// WithClock sets the clock used for polling and returns itself.
+func (s *HttpJsonSource) WithClock(clock Clock) *HttpJsonSource {
+	s.clock = clock
+	return s
+}
+
+// WithErrorHandler sets the function which is invoked with the errors which do
+// not fail Build, e.g. when the cache file cannot be written, and with the
+// errors of Poll, and returns itself. The function may be invoked concurrently.
+func (s *HttpJsonSource) WithErrorHandler(handler func(err error)) *HttpJsonSource {
+	s.errorHandler = handler
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *HttpJsonSource) Name() string {
+	return s.name
+}
+
+// Build fetches the document and builds Config. Part of [config.Source] interface.
+//
+// If the URL cannot be fetched, the last good document is used, either from
+// memory or from the cache file, and the result is flagged as stale.
+func (s *HttpJsonSource) Build() (Config, error) {
+	ctx := context.Background()
+	doc, _, err := s.fetch(ctx)
+	if err == nil {
+		return s.build(doc, nil)
+	}
+
+	s.mu.Lock()
+	last := s.last
+	s.mu.Unlock()
+
+	if last == nil {
+		return nil, newSourceError("HttpJsonSource", s.name, "", err)
+	}
+	return s.build(last, err)
+}
+
+// Poll polls the URL until the context is done, and reloads the provider of
+// this source in the root every time the document changes, in the same way as
+// [config.ReloadOnChange]. Failures, and the errors of Load, are reported to
+// the handler set with WithErrorHandler, and failures are retried with
+// exponential jittered backoff, up to the maximum set with WithPollInterval.
+// Poll returns an error if no provider of the root is built from this source,
+// otherwise the error of the context.
+//
+// The root reports the changes with [config.RootConfig.OnChange].
+func (s *HttpJsonSource) Poll(ctx context.Context, root RootConfig) error {
+	if len(sourceProviders(root, s)) == 0 {
+		return errors.Errorf("Poll: no provider is built from source %s", s.name)
+	}
+
+	failures := 0
+	for {
+		delay := s.pollInterval
+		if failures > 0 {
+			delay = s.backoff(failures)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.clock.After(delay):
+		}
+
+		_, changed, err := s.fetch(ctx)
+		if err != nil {
+			failures++
+			s.reportError(newSourceError("HttpJsonSource", s.name, "", err))
+			continue
+		}
+		failures = 0
+
+		if changed {
+			// Load builds this source again, which finds the document
+			// not modified and uses the one just fetched.
+			for _, provider := range sourceProviders(root, s) {
+				if err := provider.Load(); err != nil {
+					s.reportError(err)
+				}
+			}
+		}
+	}
+}
+
+// reportError passes the error to the handler set with WithErrorHandler.
+func (s *HttpJsonSource) reportError(err error) {
+	if s.errorHandler != nil {
+		s.errorHandler(err)
+	}
+}
+
+// backoff returns the delay after the number of consecutive failures:
+// poll interval doubled on each failure, capped at max backoff, with +/-20% jitter.
+func (s *HttpJsonSource) backoff(failures int) time.Duration {
+	delay := s.pollInterval
+	for i := 0; i < failures && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > s.maxBackoff {
+		delay = s.maxBackoff
+	}
+
+	jitter := time.Duration(float64(delay) * 0.2 * (2*rand.Float64() - 1))
+	return delay + jitter
+}
+
+// fetch fetches the document, returning whether it changed since the last fetch.
+// The lock is not held during the request, so Build is not blocked by a slow
+// poll. The document is returned even when the cache file cannot be written.
+func (s *HttpJsonSource) fetch(ctx context.Context) (*httpJsonDocument, bool, error) {
+	s.mu.Lock()
+	if s.last == nil && s.cacheFile != "" {
+		// The error is ignored, the cache is only an optimisation here.
+		s.last, _ = s.readCache()
+	}
+	last := s.last
+	s.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if last != nil && last.ETag != "" {
+		req.Header.Set("If-None-Match", last.ETag)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		if last == nil {
+			return nil, false, errors.Errorf("GET %s: not modified, but there is no previous document", s.url)
+		}
+		return last, false, nil
+	case http.StatusOK:
+	default:
+		return nil, false, errors.Errorf("GET %s: unexpected status %s", s.url, resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "GET %s", s.url)
+	}
+
+	// Only keep the documents which can be parsed.
+	if _, err := newJsonLoader().Load(bytes.NewReader(b)); err != nil {
+		return nil, false, errors.Wrapf(err, "GET %s", s.url)
+	}
+
+	doc := &httpJsonDocument{
+		Url:       s.url,
+		ETag:      resp.Header.Get("ETag"),
+		FetchedAt: s.clock.Now().UTC(),
+		Document:  string(b),
+	}
+
+	s.mu.Lock()
+	changed := s.last == nil || s.last.Document != doc.Document
+	s.last = doc
+	s.mu.Unlock()
+
+	if s.cacheFile != "" {
+		// The cache is only a fallback, failing to write it does not make
+		// the fetched document any worse.
+		if err := s.writeCache(doc); err != nil {
+			s.reportError(newSourceError("HttpJsonSource", s.name, s.cacheFile, err))
+		}
+	}
+
+	return doc, changed, nil
+}
+
+func (s *HttpJsonSource) build(doc *httpJsonDocument, fetchErr error) (Config, error) {
+	loader := newJsonLoader()
+	if _, err := loader.Load(bytes.NewBufferString(doc.Document)); err != nil {
+		return nil, newSourceError("HttpJsonSource", s.name, "", err)
+	}
+
+	return &httpJsonConfig{
+		locatedConfig: loader.config(s),
+		etag:          doc.ETag,
+		fetchedAt:     doc.FetchedAt,
+		fetchErr:      fetchErr,
+	}, nil
+}
+
+func (s *HttpJsonSource) readCache() (*httpJsonDocument, error) {
+	b, err := os.ReadFile(s.cacheFile)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &httpJsonDocument{}
+	if err := json.Unmarshal(b, doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Url != s.url {
+		return nil, errors.Errorf("cache file %s is for different url %s", s.cacheFile, doc.Url)
+	}
+	return doc, nil
+}
+
+// writeCache writes the cache file atomically so readers never see partial content.
+func (s *HttpJsonSource) writeCache(doc *httpJsonDocument) error {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.cacheFile), filepath.Base(s.cacheFile)+".*.tmp")
+	if err != nil {
+		return errors.Wrapf(err, "cannot write cache file %s", s.cacheFile)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return errors.Wrapf(err, "cannot write cache file %s", s.cacheFile)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "cannot write cache file %s", s.cacheFile)
+	}
+
+	return errors.Wrapf(os.Rename(tmp.Name(), s.cacheFile), "cannot write cache file %s", s.cacheFile)
+}
+
+// HttpJsonEntry is implemented by the entries of [config.HttpJsonSource]. It
+// describes the document which provided the value.
+type HttpJsonEntry interface {
+	JsonEntry
+	// Stale reports whether the value comes from the last good document,
+	// because the URL could not be fetched.
+	Stale() bool
+	// Err is the error fetching the URL when the value is stale.
+	Err() error
+	// ETag is the ETag of the document.
+	ETag() string
+	// FetchedAt is the time the document was downloaded.
+	FetchedAt() time.Time
+}
+
+// httpJsonConfig adds the document of [config.HttpJsonSource] to the entries.
+type httpJsonConfig struct {
+	*locatedConfig
+	etag      string
+	fetchedAt time.Time
+	fetchErr  error
+}
+
+func (c *httpJsonConfig) tryGetEntry(key string) (Entry, bool) {
+	entry, found := c.locatedConfig.tryGetEntry(key)
+	if e, ok := entry.(*jsonEntryImpl); ok {
+		return &httpJsonEntryImpl{jsonEntryImpl: e, config: c}, found
+	}
+	return entry, found
+}
+
+// httpJsonEntryImpl implements [config.HttpJsonEntry] interface.
+type httpJsonEntryImpl struct {
+	*jsonEntryImpl
+	config *httpJsonConfig
+}
+
+func (e *httpJsonEntryImpl) Stale() bool {
+	return e.config.fetchErr != nil
+}
+
+func (e *httpJsonEntryImpl) Err() error {
+	return e.config.fetchErr
+}
+
+func (e *httpJsonEntryImpl) ETag() string {
+	return e.config.etag
+}
+
+func (e *httpJsonEntryImpl) FetchedAt() time.Time {
+	return e.config.fetchedAt
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// httpJsonTestServer serves a Json document with ETag, and can be switched off.
+type httpJsonTestServer struct {
+	mu           sync.Mutex
+	document     string
+	etag         string
+	down         bool
+	requests     int
+	notModifieds int
+}
+
+func (s *httpJsonTestServer) set(document string, etag string, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.document, s.etag, s.down = document, etag, down
+}
+
+func (s *httpJsonTestServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *httpJsonTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Header.Get("If-None-Match") == s.etag {
+		s.notModifieds++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", s.etag)
+	_, _ = w.Write([]byte(s.document))
+}
+
+// getHttpJsonEntry returns the entry of the config built by HttpJsonSource.
+func getHttpJsonEntry(config Config, key string) HttpJsonEntry {
+	entry, _ := getConfigEntry(config, key)
+	return entry.(HttpJsonEntry)
+}
+
+func Test_HttpJsonSource_ETagAndCache(t *testing.T) {
+	ts := &httpJsonTestServer{}
+	ts.set(`{"foo": "bar", "nested": {"a": 1}}`, `"v1"`, false)
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	cacheFile := filepath.Join(t.TempDir(), "cache.json")
+
+	config, err := NewHttpJsonSource(server.URL).WithCacheFile(cacheFile).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", config.Get("foo"))
+	assert.Equal(t, "1", config.Get("nested:a"))
+
+	assert.IsType(t, &HttpJsonSource{}, config.Source())
+	entry := getHttpJsonEntry(config, "nested:a")
+	assert.False(t, entry.Stale())
+	assert.Equal(t, `"v1"`, entry.ETag())
+	assert.Equal(t, JsonValueKindNumber, entry.JsonKind())
+	assert.Equal(t, 1, entry.Location().Line)
+
+	// A new source instance picks up the ETag from the cache file.
+	config, err = NewHttpJsonSource(server.URL).WithCacheFile(cacheFile).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", config.Get("foo"))
+	assert.Equal(t, 1, ts.notModifieds)
+
+	// When the endpoint is down, the cached document is used and flagged as stale.
+	ts.set("", "", true)
+	source := NewHttpJsonSource(server.URL).WithCacheFile(cacheFile).WithName("remote")
+	config, err = source.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", config.Get("foo"))
+
+	assert.Equal(t, source, config.Source())
+	entry = getHttpJsonEntry(config, "foo")
+	assert.True(t, entry.Stale())
+	assert.Error(t, entry.Err())
+	assert.Equal(t, `"v1"`, entry.ETag())
+
+	// Without cache there is nothing to fall back to.
+	_, err = NewHttpJsonSource(server.URL).Build()
+	assert.Error(t, err)
+}
+
+func Test_HttpJsonSource_InvalidDocumentIsNotCached(t *testing.T) {
+	ts := &httpJsonTestServer{}
+	ts.set(`{"foo": "bar"}`, `"v1"`, false)
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	source := NewHttpJsonSource(server.URL)
+	_, err := source.Build()
+	assert.NoError(t, err)
+
+	ts.set(`{"foo": `, `"v2"`, false)
+	config, err := source.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", config.Get("foo"))
+	assert.True(t, getHttpJsonEntry(config, "foo").Stale())
+}
+
+func Test_HttpJsonSource_Poll(t *testing.T) {
+	ts := &httpJsonTestServer{}
+	ts.set(`{"foo": "v1"}`, `"v1"`, false)
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	clock := newFakeClock()
+	errs := make(chan error, 10)
+	source := NewHttpJsonSource(server.URL).
+		WithPollInterval(time.Second, 10*time.Second).
+		WithClock(clock).
+		WithErrorHandler(func(err error) { errs <- err })
+	builder := NewBuilder()
+	builder.AddSource(NewOptionalSource(source))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, clock.Now(), root.GetEntry("foo").(HttpJsonEntry).FetchedAt())
+
+	changes := make(chan []ConfigChange, 10)
+	root.OnChange(func(c []ConfigChange) { changes <- c })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- source.Poll(ctx, root)
+	}()
+
+	// A failure is reported, and is retried with backoff.
+	clock.waitForWaiters(t)
+	ts.set("", "", true)
+	clock.Advance(time.Second)
+	assert.Contains(t, (<-errs).Error(), "unexpected status 503")
+
+	clock.waitForWaiters(t)
+	ts.set(`{"foo": "v2"}`, `"v2"`, false)
+	clock.Advance(time.Second)
+	assert.Equal(t, 2, ts.requestCount())
+
+	// The change reloads the provider, which gets the document not modified.
+	clock.Advance(10 * time.Second)
+	c := <-changes
+	if assert.Len(t, c, 1) {
+		assert.Equal(t, "v2", c[0].New.Value())
+	}
+	assert.Equal(t, "v2", root.Get("foo"))
+	entry := root.GetEntry("foo").(HttpJsonEntry)
+	assert.Equal(t, `"v2"`, entry.ETag())
+	assert.Equal(t, clock.Now(), entry.FetchedAt())
+	assert.Equal(t, 4, ts.requestCount())
+
+	// Unchanged documents do not reload.
+	clock.waitForWaiters(t)
+	clock.Advance(time.Second)
+	clock.waitForWaiters(t)
+	assert.Equal(t, 5, ts.requestCount())
+	assert.Empty(t, changes)
+
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Empty(t, errs)
+
+	// The source must be in the root.
+	err = NewHttpJsonSource(server.URL).Poll(context.Background(), root)
+	assert.Error(t, err)
+}
+
+func Test_HttpJsonSource_CacheWriteError(t *testing.T) {
+	ts := &httpJsonTestServer{}
+	ts.set(`{"foo": "bar"}`, `"v1"`, false)
+	server := httptest.NewServer(ts)
+	defer server.Close()
+
+	var errs []error
+	cacheFile := filepath.Join(t.TempDir(), "missing", "cache.json")
+	config, err := NewHttpJsonSource(server.URL).
+		WithCacheFile(cacheFile).
+		WithErrorHandler(func(err error) { errs = append(errs, err) }).
+		Build()
+
+	// The fetched document is used, and the error is only reported.
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", config.Get("foo"))
+	assert.False(t, getHttpJsonEntry(config, "foo").Stale())
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "cannot write cache file")
+	}
+}
+
+func Test_HttpJsonSource_BuildDuringSlowFetch(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-release
+		}
+		_, _ = w.Write([]byte(`{"foo": "bar"}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	source := NewHttpJsonSource(server.URL)
+	go func() {
+		_, _ = source.Build()
+	}()
+	<-entered
+
+	// Another Build does not wait for the slow request.
+	built := make(chan Config)
+	go func() {
+		config, err := source.Build()
+		assert.NoError(t, err)
+		built <- config
+	}()
+
+	select {
+	case config := <-built:
+		assert.Equal(t, "bar", config.Get("foo"))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Build is blocked by the slow request")
+	}
+}
+
+func Test_HttpJsonSource_backoff(t *testing.T) {
+	source := NewHttpJsonSource("http://localhost").WithPollInterval(time.Second, 10*time.Second)
+	for failures := 1; failures < 10; failures++ {
+		delay := source.backoff(failures)
+		assert.True(t, delay >= 1600*time.Millisecond, "delay %v", delay)
+		assert.True(t, delay <= 12*time.Second, "delay %v", delay)
+	}
+}
@@ -1,12 +1,10 @@
 package config
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"strings"
 
 	"github.com/pkg/errors"
 )
@@ -25,77 +23,132 @@ import (
 //	"foo": "bar"
 //	"ConnectionStrings:SqlServer": "<some value>"
 //
-// See: https://github.com/dotnet/runtime/blob/release/6.0/src/libraries/Microsoft.Extensions.Configuration.Json/src/JsonConfigurationFileParser.cs
+// See: https://github.com/dotnet/runtime/blob/release/7.0/src/libraries/Microsoft.Extensions.Configuration.Json/src/JsonConfigurationFileParser.cs
 type jsonLoader struct {
-	data  map[string]string
-	paths stringStack
+	// data has the keys with original casing, positions and kinds are the
+	// positions and the kinds of the values by normalised keys.
+	data      map[string]string
+	positions map[string]Location
+	kinds     map[string]JsonValueKind
+	paths     stringStack
+
+	// path is the file of the locations and the errors, if any.
+	path string
+
+	// input and decoder of the json being loaded.
+	input   []byte
+	decoder *json.Decoder
 }
 
 func newJsonLoader() *jsonLoader {
 	return &jsonLoader{
-		data: make(map[string]string),
+		data:      make(map[string]string),
+		positions: make(map[string]Location),
+		kinds:     make(map[string]JsonValueKind),
 	}
 }
 
+// newJsonFileLoader creates jsonLoader for the file, which is used in the
+// locations of the values and in the errors.
+func newJsonFileLoader(path string) *jsonLoader {
+	j := newJsonLoader()
+	j.path = path
+	return j
+}
+
+// config creates [config.Config] with the loaded values, where the entries
+// implement [config.JsonEntry].
+func (j *jsonLoader) config(source Source) *locatedConfig {
+	config := newLocatedConfig(newConfigImpl(source, j.data), j.positions)
+	config.kinds = j.kinds
+	return config
+}
+
+// Load loads the values. The errors are [config.ParseError] or
+// [config.DuplicateKeyError].
 func (j *jsonLoader) Load(r io.Reader) (map[string]string, error) {
-	rootElement, err := j.parseJson(r)
+	b, err := readJsonc(r)
 	if err != nil {
+		if parseErr, ok := err.(*ParseError); ok {
+			parseErr.Path = j.path
+		}
 		return j.data, err
 	}
 
-	err = j.visitElement(rootElement)
-	return j.data, err
-}
+	j.input = b.Bytes()
+	j.decoder = json.NewDecoder(bytes.NewReader(j.input))
+	j.decoder.UseNumber()
 
-func (j *jsonLoader) parseJson(r io.Reader) (map[string]interface{}, error) {
-	// strip out comments as these are common
-	b := bytes.Buffer{}
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(strings.TrimSpace(line), "//") {
-			continue
+	err = j.load()
+	switch err.(type) {
+	case nil, *DuplicateKeyError:
+		return j.data, err
+	default:
+		parseErr := newJsonParseError(j.input, j.path, j.nextTokenOffset(), err)
+		if j.paths.Count() > 0 {
+			parseErr.Key = j.paths.Peek()
 		}
-		b.WriteString(line)
-		b.WriteString("\n")
+		return j.data, parseErr
 	}
+}
 
-	// Give a friendly message if json input is an array.
-	var rootElement interface{}
-	err := json.Unmarshal(b.Bytes(), &rootElement)
+func (j *jsonLoader) load() error {
+	start := j.nextTokenOffset()
+	token, err := j.decoder.Token()
+	if err == io.EOF {
+		return errors.New("unexpected end of JSON input")
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	switch val := rootElement.(type) {
-	case []interface{}:
-		return nil, errors.New("arrays are not supported as root json object")
-	case map[string]interface{}:
-		err := json.Unmarshal(b.Bytes(), &rootElement)
-		if err != nil {
-			return val, err
-		}
-		return val, nil
+	// Give a friendly message if json input is an array.
+	switch token {
+	case json.Delim('['):
+		return errors.New("arrays are not supported as root json object")
+	case json.Delim('{'):
 	default:
-		return nil, errors.New("unexpected, input json is neither array nor an object")
+		return errors.New("unexpected, input json is neither array nor an object")
+	}
+
+	err = j.visitElement(start)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.decoder.Token(); err != io.EOF {
+		return errors.New("invalid data after top-level value")
 	}
+	return nil
 }
 
-func (j *jsonLoader) visitElement(element map[string]interface{}) error {
+// visitElement visits the members of the object which opening brace is
+// already read and starts at the offset.
+func (j *jsonLoader) visitElement(start int64) error {
 	isEmpty := true
 
-	for k, v := range element {
+	for j.decoder.More() {
 		isEmpty = false
-		j.enterContext(k)
-		err := j.visitValue(v)
+
+		token, err := j.decoder.Token()
+		if err != nil {
+			return err
+		}
+		j.enterContext(token.(string))
+
+		err = j.visitValue()
 		if err != nil {
 			return err
 		}
 		j.exitContext()
 	}
 
+	if _, err := j.decoder.Token(); err != nil {
+		return err
+	}
+
 	if isEmpty && j.paths.Count() > 0 {
-		j.data[j.paths.Peek()] = ""
+		return j.setValue("", JsonValueKindObject, start)
 	}
 
 	return nil
@@ -107,7 +160,6 @@ func (j *jsonLoader) enterContext(k string) {
 		path = fmt.Sprintf("%s%s%s", j.paths.Peek(), keyDelimiter, path)
 	}
 
-	path = normalizeKey(path)
 	j.paths.Push(path)
 }
 
@@ -115,34 +167,88 @@ func (j *jsonLoader) exitContext() {
 	j.paths.Pop()
 }
 
-func (j *jsonLoader) visitValue(v interface{}) error {
-	switch val := v.(type) {
-	case map[string]interface{}:
-		err := j.visitElement(val)
-		if err != nil {
-			return err
+func (j *jsonLoader) visitValue() error {
+	start := j.nextTokenOffset()
+	token, err := j.decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch val := token.(type) {
+	case json.Delim:
+		if val == '{' {
+			return j.visitElement(start)
 		}
-	case []interface{}:
-		for index, arrayElement := range val {
+
+		index := 0
+		for ; j.decoder.More(); index++ {
 			j.enterContext(fmt.Sprintf("%d", index))
-			err := j.visitValue(arrayElement)
+			err := j.visitValue()
 			if err != nil {
 				return err
 			}
 			j.exitContext()
 		}
-	default:
-		key := j.paths.Peek()
-		if _, found := j.data[key]; found {
-			return errors.Errorf("duplicate key '%s'", key)
+		if _, err := j.decoder.Token(); err != nil {
+			return err
 		}
 
-		value := fmt.Sprintf("%v", val)
-		if val == nil {
-			value = ""
+		// Empty arrays are kept like empty objects, same as ASP.NET 7.0.
+		if index == 0 {
+			return j.setValue("", JsonValueKindArray, start)
+		}
+		return nil
+	case nil:
+		return j.setValue("", JsonValueKindNull, start)
+	case bool:
+		// Same as .NET JsonElement.ToString.
+		if val {
+			return j.setValue("True", JsonValueKindTrue, start)
 		}
-		j.data[key] = value
+		return j.setValue("False", JsonValueKindFalse, start)
+	case json.Number:
+		// The number as it is written, without loss of precision.
+		return j.setValue(val.String(), JsonValueKindNumber, start)
+	default:
+		return j.setValue(fmt.Sprintf("%v", val), JsonValueKindString, start)
+	}
+}
+
+func (j *jsonLoader) setValue(value string, kind JsonValueKind, start int64) error {
+	key := j.paths.Peek()
+	normalized := normalizeKey(key)
+	if first, found := j.positions[normalized]; found {
+		return &DuplicateKeyError{Key: key, First: first, Second: j.location(start)}
 	}
 
+	j.data[key] = value
+	j.positions[normalized] = j.location(start)
+	j.kinds[normalized] = kind
 	return nil
 }
+
+// nextTokenOffset returns the offset of the next token in the input, skipping
+// whitespace and separators after the position of the decoder.
+func (j *jsonLoader) nextTokenOffset() int64 {
+	return jsonNextTokenOffset(j.input, j.decoder.InputOffset())
+}
+
+// jsonNextTokenOffset returns the offset of the next token in the input,
+// skipping whitespace and separators from the offset.
+func jsonNextTokenOffset(input []byte, offset int64) int64 {
+	for offset < int64(len(input)) {
+		switch input[offset] {
+		case ' ', '\t', '\r', '\n', ':', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// location returns 1-based line and column of the offset in the input.
+func (j *jsonLoader) location(offset int64) Location {
+	line, column := jsonPosition(j.input, int(offset))
+	return Location{File: j.path, Line: line, Column: column}
+}
@@ -2,11 +2,10 @@ package config
 
 import (
 	"bytes"
-
-	"github.com/pkg/errors"
 )
 
 // NewJsonSource creates configuration source for Json which implements [config.Source].
+// See [config.NewJsonFileSource] for Json files.
 func NewJsonSource(json []byte) *JsonSource {
 	return &JsonSource{
 		json: json,
@@ -35,10 +34,10 @@ func (s *JsonSource) Name() string {
 func (s *JsonSource) Build() (Config, error) {
 	parser := newJsonLoader()
 	r := bytes.NewBuffer(s.json)
-	m, err := parser.Load(r)
+	_, err := parser.Load(r)
 	if err != nil {
-		return nil, errors.Errorf("JsonSource: %s: %v", s.name, err)
+		return nil, newSourceError("JsonSource", s.name, "", err)
 	}
 
-	return newConfigImpl(s, m), nil
+	return parser.config(s), nil
 }
@@ -56,14 +56,14 @@ func Test_jsonConfigProvider_Load_Simple(t *testing.T) {
 	assert.Equal(t, "string value", provider.Get("string"))
 	assert.Equal(t, "1", provider.Get("number"))
 	assert.Equal(t, "1.23", provider.Get("float"))
-	assert.Equal(t, "true", provider.Get("bool"))
+	assert.Equal(t, "True", provider.Get("bool"))
 	assert.Equal(t, "", provider.Get("null"))
 
 	// elements at the root, using different case to grab values
 	assert.Equal(t, "string value", provider.Get("STRING"))
 	assert.Equal(t, "1", provider.Get("NUMBER"))
 	assert.Equal(t, "1.23", provider.Get("FLOAT"))
-	assert.Equal(t, "true", provider.Get("BOOL"))
+	assert.Equal(t, "True", provider.Get("BOOL"))
 	assert.Equal(t, "", provider.Get("NULL"))
 
 	// array of strings
@@ -76,13 +76,13 @@ func Test_jsonConfigProvider_Load_Simple(t *testing.T) {
 	assert.Equal(t, "string value 1", provider.Get("array_of_objects:0:string"))
 	assert.Equal(t, "1", provider.Get("array_of_objects:0:number"))
 	assert.Equal(t, "1.23", provider.Get("array_of_objects:0:float"))
-	assert.Equal(t, "true", provider.Get("array_of_objects:0:bool"))
+	assert.Equal(t, "True", provider.Get("array_of_objects:0:bool"))
 	assert.Equal(t, "", provider.Get("array_of_objects:0:null"))
 
 	assert.Equal(t, "string value 2", provider.Get("array_of_objects:1:string"))
 	assert.Equal(t, "2", provider.Get("array_of_objects:1:number"))
 	assert.Equal(t, "2.23", provider.Get("array_of_objects:1:float"))
-	assert.Equal(t, "true", provider.Get("array_of_objects:1:bool"))
+	assert.Equal(t, "True", provider.Get("array_of_objects:1:bool"))
 	assert.Equal(t, "", provider.Get("array_of_objects:1:null"))
 
 	// nested1
@@ -90,7 +90,7 @@ func Test_jsonConfigProvider_Load_Simple(t *testing.T) {
 	assert.Equal(t, "nested1 string value", provider.Get("nested1:string"))
 	assert.Equal(t, "2", provider.Get("nested1:number"))
 	assert.Equal(t, "9.23", provider.Get("nested1:float"))
-	assert.Equal(t, "true", provider.Get("nested1:bool"))
+	assert.Equal(t, "True", provider.Get("nested1:bool"))
 	assert.Equal(t, "", provider.Get("nested1:null"))
 }
 
@@ -110,3 +110,46 @@ func Test_jsonConfigProvider_Load_ArraysNotSupported(t *testing.T) {
 		assert.Truef(t, hasCorrectMessage, "array objects should return correct message, was: %v", err.Error())
 	}
 }
+
+func Test_jsonConfigProvider_Load_ScalarsAndKinds(t *testing.T) {
+	json := `{
+	"million": 1000000,
+	"one": 1.0,
+	"big": 12345678901234567890,
+	"exp": 1E-7,
+	"true": true,
+	"false": false,
+	"null": null,
+	"empty": "",
+	"object": {},
+	"array": []
+}`
+
+	config, err := NewJsonSource([]byte(json)).Build()
+	assert.NoError(t, err)
+
+	for key, expected := range map[string]struct {
+		value string
+		kind  JsonValueKind
+	}{
+		"million": {"1000000", JsonValueKindNumber},
+		"one":     {"1.0", JsonValueKindNumber},
+		"big":     {"12345678901234567890", JsonValueKindNumber},
+		"exp":     {"1E-7", JsonValueKindNumber},
+		"true":    {"True", JsonValueKindTrue},
+		"false":   {"False", JsonValueKindFalse},
+		"null":    {"", JsonValueKindNull},
+		"empty":   {"", JsonValueKindString},
+		"object":  {"", JsonValueKindObject},
+		"array":   {"", JsonValueKindArray},
+	} {
+		assert.Equal(t, expected.value, config.Get(key), key)
+
+		entry, _ := getConfigEntry(config, key)
+		if jsonEntry, ok := entry.(JsonEntry); assert.True(t, ok, key) {
+			assert.Equal(t, expected.kind, jsonEntry.JsonKind(), key)
+		}
+	}
+
+	assert.Equal(t, "Null", JsonValueKindNull.String())
+}
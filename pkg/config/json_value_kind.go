@@ -0,0 +1,57 @@
+package config
+
+// JsonValueKind is the kind of Json value which a value comes from, same as
+// .NET JsonValueKind. Objects and arrays are values only when they are empty.
+type JsonValueKind int
+
+const (
+	JsonValueKindUndefined JsonValueKind = iota
+	JsonValueKindObject
+	JsonValueKindArray
+	JsonValueKindString
+	JsonValueKindNumber
+	JsonValueKindTrue
+	JsonValueKindFalse
+	JsonValueKindNull
+)
+
+func (k JsonValueKind) String() string {
+	switch k {
+	case JsonValueKindObject:
+		return "Object"
+	case JsonValueKindArray:
+		return "Array"
+	case JsonValueKindString:
+		return "String"
+	case JsonValueKindNumber:
+		return "Number"
+	case JsonValueKindTrue:
+		return "True"
+	case JsonValueKindFalse:
+		return "False"
+	case JsonValueKindNull:
+		return "Null"
+	default:
+		return "Undefined"
+	}
+}
+
+// JsonEntry is implemented by entries which values come from Json. The values
+// are rendered in the same way as .NET JsonElement.ToString does, e.g. "True"
+// for true, numbers as they are written, and an empty string for null and
+// empty objects and arrays. The kind tells these apart, e.g. null from "".
+type JsonEntry interface {
+	LocatedEntry
+	// JsonKind returns the kind of Json value.
+	JsonKind() JsonValueKind
+}
+
+// jsonEntryImpl implements [config.JsonEntry] interface.
+type jsonEntryImpl struct {
+	*locatedEntryImpl
+	kind JsonValueKind
+}
+
+func (e *jsonEntryImpl) JsonKind() JsonValueKind {
+	return e.kind
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// utf8Bom is the byte order mark which is allowed at the start of Json files.
+var utf8Bom = []byte("\xef\xbb\xbf")
+
+// readJsonc reads Json with comments and trailing commas, and returns strict
+// Json which can be parsed by encoding/json. This accepts exactly what ASP.NET
+// accepts with JsonDocumentOptions CommentHandling.Skip and AllowTrailingCommas:
+//
+//   - Line comments "// ..." and block comments "/* ... */" between tokens.
+//   - A single comma after the last member of an object or an array.
+//
+// The comments, trailing commas and the byte order mark are replaced with
+// spaces, so the positions of the values do not change. Any other invalid Json
+// is kept as-is and is rejected by the parser.
+func readJsonc(r io.Reader) (*bytes.Buffer, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, len(input))
+	copy(b, input)
+	if bytes.HasPrefix(b, utf8Bom) {
+		blank(b, 0, len(utf8Bom))
+	}
+
+	// pendingComma is the offset of the comma which is trailing if the next
+	// token closes the object or the array. previous is the previous token.
+	pendingComma := -1
+	previous := byte(0)
+
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			end := bytes.IndexByte(b[i:], '\n')
+			if end < 0 {
+				end = len(b) - i
+			}
+			blank(b, i, i+end)
+			i += end - 1
+			continue
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				line, column := jsonPosition(b, i)
+				return nil, &ParseError{Line: line, Column: column, Err: errors.New("unterminated comment")}
+			}
+			blank(b, i, i+2+end+2)
+			i += 2 + end + 1
+			continue
+		}
+
+		if pendingComma >= 0 && (c == '}' || c == ']') {
+			b[pendingComma] = ' '
+		}
+		pendingComma = -1
+
+		switch c {
+		case ',':
+			// A comma right after opening brace or another comma is not trailing.
+			if previous != '{' && previous != '[' && previous != ',' {
+				pendingComma = i
+			}
+		case '"':
+			i = skipJsonString(b, i)
+		}
+		previous = c
+	}
+
+	return bytes.NewBuffer(b), nil
+}
+
+// skipJsonString returns the offset of the closing quote of the string which
+// starts at the offset, or the end of the input if it is not terminated.
+func skipJsonString(b []byte, start int) int {
+	for i := start + 1; i < len(b); i++ {
+		switch b[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return len(b)
+}
+
+// blank replaces the bytes between start and end with spaces, keeping the
+// line breaks.
+func blank(b []byte, start int, end int) {
+	for i := start; i < end; i++ {
+		if b[i] != '\n' && b[i] != '\r' {
+			b[i] = ' '
+		}
+	}
+}
+
+// jsonPosition returns 1-based line and column of the offset in the input.
+func jsonPosition(b []byte, offset int) (int, int) {
+	line := 1 + bytes.Count(b[:offset], []byte("\n"))
+	column := offset + 1
+	if i := bytes.LastIndexByte(b[:offset], '\n'); i >= 0 {
+		column = offset - i
+	}
+	return line, column
+}
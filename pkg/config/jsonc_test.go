@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Jsonc_Accepted(t *testing.T) {
+	json := "\xef\xbb\xbf" + `// leading comment
+{
+	"a": 1, // trailing comment
+	/* block
+	   comment */ "b": "not // a comment",
+	"c": "/* not a comment */",
+	"d": [1, 2, /* inline */ 3,],
+	"e": {"f": "\" // escaped quote",},
+	"g": [],
+} /* the end */`
+
+	root, err := NewJsonSource([]byte(json)).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "1", root.Get("a"))
+	assert.Equal(t, "not // a comment", root.Get("b"))
+	assert.Equal(t, "/* not a comment */", root.Get("c"))
+	assert.Equal(t, "3", root.Get("d:2"))
+	assert.Equal(t, `" // escaped quote`, root.Get("e:f"))
+
+	// The positions do not change.
+	entry, _ := getConfigEntry(root, "b")
+	if located, ok := entry.(LocatedEntry); assert.True(t, ok) {
+		assert.Equal(t, "5:21", located.Location().String())
+	}
+}
+
+func Test_Jsonc_Rejected(t *testing.T) {
+	for _, json := range []string{
+		`{,}`,
+		`{"a": [,]}`,
+		`{"a": [1,,]}`,
+		`{"a": 1,,}`,
+		`{"a": 1},`,
+		`{"a": 1 /* unterminated`,
+		`{'a': 1}`,
+		`{a: 1}`,
+		`{"a": 1} / 2`,
+		`{"a": # 1}`,
+	} {
+		_, err := NewJsonSource([]byte(json)).Build()
+		assert.Error(t, err, json)
+	}
+}
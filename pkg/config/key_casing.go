@@ -0,0 +1,47 @@
+package config
+
+import (
+	"sort"
+)
+
+// KeyCasingConflict is a key which is spelled with different casing by
+// different providers, e.g. "ConnectionStrings:Sql" in appsettings.json and
+// "CONNECTIONSTRINGS__SQL" in environment variables. The lookups are
+// case-insensitive so the value is the same, but the casing of the first
+// provider is reported by [config.RootConfig], which can be surprising.
+type KeyCasingConflict struct {
+	// Key is the key with the casing which is reported, from the first provider.
+	Key string
+	// Entries are the entries of every provider which has the key, in
+	// precedence order. The key of each entry has the casing of its provider.
+	Entries []Entry
+}
+
+// FindKeyCasingConflicts returns the keys of the configuration which are
+// spelled with different casing by different providers, e.g. for linting.
+// The list is sorted by keys.
+func FindKeyCasingConflicts(root RootConfig) []KeyCasingConflict {
+	entries := make(map[string][]Entry)
+	for _, provider := range root.Providers() {
+		for _, key := range provider.Keys() {
+			normalized := normalizeKey(key)
+			entry, _ := getProviderEntry(provider, key)
+			entries[normalized] = append(entries[normalized], entry)
+		}
+	}
+
+	var conflicts []KeyCasingConflict
+	for _, keyEntries := range entries {
+		for _, entry := range keyEntries[1:] {
+			if entry.Key() != keyEntries[0].Key() {
+				conflicts = append(conflicts, KeyCasingConflict{Key: keyEntries[0].Key(), Entries: keyEntries})
+				break
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i int, j int) bool {
+		return normalizeKey(conflicts[i].Key) < normalizeKey(conflicts[j].Key)
+	})
+	return conflicts
+}
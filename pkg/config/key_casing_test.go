@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newKeyCasingTestRoot(t *testing.T) RootConfig {
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{
+		"ConnectionStrings": {"Sql": "json"},
+		"Logging": {"LogLevel": {"Default": "Information"}}
+	}`)).WithName("json"))
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{
+		"CONNECTIONSTRINGS__SQL":       "env",
+		"logging__loglevel__Microsoft": "Warning",
+		"Hosts__0":                     "a",
+	}).WithName("env"))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+	return root
+}
+
+func Test_KeyCasing_FirstProviderWins(t *testing.T) {
+	root := newKeyCasingTestRoot(t)
+
+	assert.Equal(t, []string{"ConnectionStrings:Sql", "Hosts:0", "Logging:LogLevel:Default", "logging:loglevel:Microsoft"}, root.Keys())
+	assert.Equal(t, "env", root.Get("connectionstrings:sql"))
+
+	entry := root.GetEntries()[0]
+	assert.Equal(t, "ConnectionStrings:Sql", entry.Key())
+	assert.Equal(t, "env", entry.Value())
+	assert.Equal(t, "env", entry.Source().Name())
+
+	assert.Equal(t, []string{"ConnectionStrings", "Hosts", "Logging"}, root.GetChildKeys(""))
+	assert.Equal(t, []string{"LogLevel"}, root.GetChildKeys("LOGGING"))
+	assert.Equal(t, []string{"Default", "Microsoft"}, root.GetChildKeys("logging:loglevel"))
+	assert.Equal(t, "ConnectionStrings:Sql", root.GetEntryChain("CONNECTIONSTRINGS:SQL").Key)
+
+	// Set keeps the casing of the existing key.
+	provider := root.Providers()[1]
+	provider.Set("connectionstrings:SQL", "set")
+	provider.Set("New:Key", "new")
+	assert.Equal(t, []string{"CONNECTIONSTRINGS:SQL", "Hosts:0", "logging:loglevel:Microsoft", "New:Key"}, provider.Keys())
+	val := ""
+	assert.True(t, provider.TryGet("ConnectionStrings:Sql", &val))
+	assert.Equal(t, "set", val)
+}
+
+func Test_FindKeyCasingConflicts(t *testing.T) {
+	root := newKeyCasingTestRoot(t)
+
+	conflicts := FindKeyCasingConflicts(root)
+	if assert.Len(t, conflicts, 1) {
+		assert.Equal(t, "ConnectionStrings:Sql", conflicts[0].Key)
+		if assert.Len(t, conflicts[0].Entries, 2) {
+			assert.Equal(t, "ConnectionStrings:Sql", conflicts[0].Entries[0].Key())
+			assert.Equal(t, "json", conflicts[0].Entries[0].Value())
+			assert.Equal(t, "CONNECTIONSTRINGS:SQL", conflicts[0].Entries[1].Key())
+			assert.Equal(t, "env", conflicts[0].Entries[1].Source().Name())
+		}
+	}
+}
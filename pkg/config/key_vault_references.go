@@ -0,0 +1,572 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// keyVaultReferencePrefix starts every App Service Key Vault reference.
+//
+// See: https://learn.microsoft.com/en-us/azure/app-service/app-service-key-vault-references
+const keyVaultReferencePrefix = "@Microsoft.KeyVault("
+
+// ErrKeyVaultSecretNotFound is returned by [config.KeyVaultResolver] when the
+// referenced secret does not exist.
+var ErrKeyVaultSecretNotFound = errors.New("secret not found")
+
+// KeyVaultReference is a parsed App Service Key Vault reference. Both syntaxes
+// supported by App Service are recognised:
+//
+//	@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/mysecret/ec96f02080254f109c51a1f14cdb1931)
+//	@Microsoft.KeyVault(VaultName=myvault;SecretName=mysecret;SecretVersion=ec96f02080254f109c51a1f14cdb1931)
+type KeyVaultReference struct {
+	// Raw is the reference exactly as it appeared in the configuration value.
+	Raw string
+	// VaultURI is the base URI of the vault, e.g. https://myvault.vault.azure.net.
+	VaultURI string
+	// VaultName is the name of the vault, e.g. myvault.
+	VaultName string
+	// SecretName is the name of the secret in the vault.
+	SecretName string
+	// SecretVersion is the version of the secret, empty means the latest.
+	SecretVersion string
+}
+
+// IsKeyVaultReference reports whether the value looks like an App Service
+// Key Vault reference. It does not validate the contents of the reference,
+// use [config.ParseKeyVaultReference] for that.
+func IsKeyVaultReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return len(value) > len(keyVaultReferencePrefix) &&
+		strings.EqualFold(value[:len(keyVaultReferencePrefix)], keyVaultReferencePrefix) &&
+		strings.HasSuffix(value, ")")
+}
+
+// ParseKeyVaultReference parses the App Service Key Vault reference.
+// The parameter names are case-insensitive like in App Service.
+func ParseKeyVaultReference(value string) (KeyVaultReference, error) {
+	ref := KeyVaultReference{Raw: value}
+	if !IsKeyVaultReference(value) {
+		return ref, errors.Errorf("not a Key Vault reference: '%s'", value)
+	}
+
+	trimmed := strings.TrimSpace(value)
+	body := trimmed[len(keyVaultReferencePrefix) : len(trimmed)-1]
+
+	params := make(map[string]string)
+	for _, param := range strings.Split(body, ";") {
+		if strings.TrimSpace(param) == "" {
+			continue
+		}
+		fields := strings.SplitN(param, "=", 2)
+		if len(fields) != 2 {
+			return ref, errors.Errorf("malformed Key Vault reference parameter '%s'", param)
+		}
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		params[name] = strings.TrimSpace(fields[1])
+	}
+
+	if secretUri, found := params["secreturi"]; found {
+		if len(params) != 1 {
+			return ref, errors.New("SecretUri cannot be combined with other Key Vault reference parameters")
+		}
+		return parseKeyVaultSecretUri(ref, secretUri)
+	}
+
+	for name := range params {
+		switch name {
+		case "vaultname", "secretname", "secretversion":
+		default:
+			return ref, errors.Errorf("unknown Key Vault reference parameter '%s'", name)
+		}
+	}
+
+	ref.VaultName = params["vaultname"]
+	ref.SecretName = params["secretname"]
+	ref.SecretVersion = params["secretversion"]
+	if ref.VaultName == "" || ref.SecretName == "" {
+		return ref, errors.New("Key Vault reference requires either SecretUri or both VaultName and SecretName")
+	}
+
+	ref.VaultURI = fmt.Sprintf("https://%s.vault.azure.net", ref.VaultName)
+	return ref, nil
+}
+
+// parseKeyVaultSecretUri fills in the reference from the secret URI which looks like
+// https://<vault>.<domain>/secrets/<name>[/<version>].
+func parseKeyVaultSecretUri(ref KeyVaultReference, secretUri string) (KeyVaultReference, error) {
+	u, err := url.Parse(secretUri)
+	if err != nil {
+		return ref, errors.Wrapf(err, "invalid SecretUri '%s'", secretUri)
+	}
+
+	if !strings.EqualFold(u.Scheme, "https") || u.Host == "" {
+		return ref, errors.Errorf("invalid SecretUri '%s': must be an absolute https URI", secretUri)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 || !strings.EqualFold(segments[0], "secrets") || segments[1] == "" {
+		return ref, errors.Errorf("invalid SecretUri '%s': expected path /secrets/<name>[/<version>]", secretUri)
+	}
+
+	ref.VaultURI = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	ref.VaultName = strings.SplitN(u.Hostname(), ".", 2)[0]
+	ref.SecretName = segments[1]
+	if len(segments) == 3 {
+		ref.SecretVersion = segments[2]
+	}
+
+	return ref, nil
+}
+
+// KeyVaultResolver resolves Key Vault references into secret values.
+// Implementations may talk to Azure, or to something local for offline use,
+// see [config.NewKeyVaultFileResolver].
+type KeyVaultResolver interface {
+	// Name is the name of this resolver, used for diagnostics.
+	Name() string
+	// Resolve returns the value of the referenced secret. Should return an
+	// error wrapping [config.ErrKeyVaultSecretNotFound] if there is no such secret.
+	Resolve(ref KeyVaultReference) (string, error)
+}
+
+// NewKeyVaultMapResolver creates [config.KeyVaultResolver] which resolves secrets
+// from user-supplied map of vault name to secret name to value.
+//
+// Vault and secret names are case-insensitive like in Azure. A specific version
+// of a secret can be supplied using "<secret name>/<version>" as the secret name.
+// References without a version, or for a version which is not in the map,
+// resolve to the value under the plain secret name.
+func NewKeyVaultMapResolver(secrets map[string]map[string]string) *KeyVaultMapResolver {
+	return &KeyVaultMapResolver{
+		name:    "KeyVaultMapResolver",
+		secrets: normalizeKeyVaultSecrets(secrets),
+	}
+}
+
+// KeyVaultMapResolver implements [config.KeyVaultResolver] interface.
+type KeyVaultMapResolver struct {
+	name    string
+	secrets map[string]map[string]string
+}
+
+// WithName sets the name of this resolver and returns itself.
+func (r *KeyVaultMapResolver) WithName(name string) *KeyVaultMapResolver {
+	r.name = name
+	return r
+}
+
+// Name is the name of this resolver. Part of [config.KeyVaultResolver] interface.
+func (r *KeyVaultMapResolver) Name() string {
+	return r.name
+}
+
+// Resolve resolves the reference. Part of [config.KeyVaultResolver] interface.
+func (r *KeyVaultMapResolver) Resolve(ref KeyVaultReference) (string, error) {
+	return resolveKeyVaultSecret(r.secrets, ref)
+}
+
+// NewKeyVaultFileResolver creates [config.KeyVaultResolver] which resolves secrets
+// from a local Json file, for offline use. The file maps vault names to secret
+// names to values:
+//
+//	{
+//		"myvault": {
+//			"mysecret": "value of the latest version",
+//			"mysecret/ec96f02080254f109c51a1f14cdb1931": "value of the specific version"
+//		}
+//	}
+//
+// The file is read once, on the first call to Resolve. The lookup rules are
+// the same as for [config.NewKeyVaultMapResolver].
+func NewKeyVaultFileResolver(path string) *KeyVaultFileResolver {
+	return &KeyVaultFileResolver{
+		name: fmt.Sprintf("KeyVaultFileResolver: %s", path),
+		path: path,
+	}
+}
+
+// KeyVaultFileResolver implements [config.KeyVaultResolver] interface.
+type KeyVaultFileResolver struct {
+	name    string
+	path    string
+	once    sync.Once
+	secrets map[string]map[string]string
+	err     error
+}
+
+// WithName sets the name of this resolver and returns itself.
+func (r *KeyVaultFileResolver) WithName(name string) *KeyVaultFileResolver {
+	r.name = name
+	return r
+}
+
+// Name is the name of this resolver. Part of [config.KeyVaultResolver] interface.
+func (r *KeyVaultFileResolver) Name() string {
+	return r.name
+}
+
+// Resolve resolves the reference. Part of [config.KeyVaultResolver] interface.
+func (r *KeyVaultFileResolver) Resolve(ref KeyVaultReference) (string, error) {
+	r.once.Do(r.load)
+	if r.err != nil {
+		return "", r.err
+	}
+	return resolveKeyVaultSecret(r.secrets, ref)
+}
+
+func (r *KeyVaultFileResolver) load() {
+	b, err := os.ReadFile(r.path)
+	if err != nil {
+		r.err = errors.Wrapf(err, "%s", r.name)
+		return
+	}
+
+	var secrets map[string]map[string]string
+	if err := json.Unmarshal(b, &secrets); err != nil {
+		r.err = errors.Wrapf(err, "%s", r.name)
+		return
+	}
+
+	r.secrets = normalizeKeyVaultSecrets(secrets)
+}
+
+func normalizeKeyVaultSecrets(secrets map[string]map[string]string) map[string]map[string]string {
+	m := make(map[string]map[string]string, len(secrets))
+	for vault, vaultSecrets := range secrets {
+		vault = strings.ToLower(vault)
+		if m[vault] == nil {
+			m[vault] = make(map[string]string, len(vaultSecrets))
+		}
+		for name, value := range vaultSecrets {
+			m[vault][strings.ToLower(name)] = value
+		}
+	}
+	return m
+}
+
+func resolveKeyVaultSecret(secrets map[string]map[string]string, ref KeyVaultReference) (string, error) {
+	vaultSecrets, found := secrets[strings.ToLower(ref.VaultName)]
+	if !found {
+		return "", errors.Wrapf(ErrKeyVaultSecretNotFound, "vault '%s'", ref.VaultName)
+	}
+
+	name := strings.ToLower(ref.SecretName)
+	if ref.SecretVersion != "" {
+		if value, found := vaultSecrets[name+"/"+strings.ToLower(ref.SecretVersion)]; found {
+			return value, nil
+		}
+	}
+
+	if value, found := vaultSecrets[name]; found {
+		return value, nil
+	}
+
+	return "", errors.Wrapf(ErrKeyVaultSecretNotFound, "vault '%s', secret '%s'", ref.VaultName, ref.SecretName)
+}
+
+// KeyVaultEntry is a [config.Entry] which value was resolved from a Key Vault
+// reference. The Source is the source which provided the reference. The values
+// are always secret.
+type KeyVaultEntry interface {
+	SecretEntry
+	// Reference is the Key Vault reference which was resolved.
+	Reference() KeyVaultReference
+	// Vault is the URI of the vault which provided the value.
+	Vault() string
+	// Resolver is the name of [config.KeyVaultResolver] which resolved the reference.
+	Resolver() string
+}
+
+// keyVaultEntryImpl implements [config.KeyVaultEntry] interface.
+type keyVaultEntryImpl struct {
+	Entry
+	value     string
+	reference KeyVaultReference
+	resolver  string
+}
+
+func (e *keyVaultEntryImpl) Value() string {
+	return e.value
+}
+
+func (e *keyVaultEntryImpl) Secret() bool {
+	return true
+}
+
+func (e *keyVaultEntryImpl) Reference() KeyVaultReference {
+	return e.reference
+}
+
+func (e *keyVaultEntryImpl) Vault() string {
+	return e.reference.VaultURI
+}
+
+func (e *keyVaultEntryImpl) Resolver() string {
+	return e.resolver
+}
+
+// KeyVaultReferenceError describes a Key Vault reference which could not be resolved.
+type KeyVaultReferenceError struct {
+	// Key is the configuration key with the reference.
+	Key string
+	// Reference is the raw reference value.
+	Reference string
+	// Source is the source which provided the reference.
+	Source Source
+	// Err is the reason the reference could not be parsed or resolved.
+	Err error
+}
+
+func (e *KeyVaultReferenceError) Error() string {
+	sourceName := ""
+	if e.Source != nil {
+		sourceName = e.Source.Name()
+	}
+	return fmt.Sprintf("key '%s' from '%s': cannot resolve Key Vault reference '%s': %v", e.Key, sourceName, e.Reference, e.Err)
+}
+
+func (e *KeyVaultReferenceError) Unwrap() error {
+	return e.Err
+}
+
+// KeyVaultReferenceErrors is a list of all references which could not be resolved.
+type KeyVaultReferenceErrors []*KeyVaultReferenceError
+
+func (e KeyVaultReferenceErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d unresolved Key Vault references: %s", len(e), strings.Join(messages, "; "))
+}
+
+// ResolveKeyVaultReferences resolves all App Service Key Vault references in
+// the values of the configuration, in the same way App Service does before the
+// application starts.
+//
+// The result is a new [config.RootConfig] with resolved values layered on top of
+// copies of the providers of the original configuration. The entries of resolved
+// values implement [config.KeyVaultEntry]. The original RootConfig is not
+// modified, neither now nor when the result reloads.
+//
+// References which cannot be resolved keep their raw value, and are reported as
+// [config.KeyVaultReferenceErrors]. The returned RootConfig is usable even when
+// the error is not nil.
+//
+// Reload of the returned RootConfig reloads its providers and resolves the
+// references again, which is also done when any of its providers reloads by
+// itself.
+func ResolveKeyVaultReferences(root RootConfig, resolver KeyVaultResolver) (RootConfig, error) {
+	base, err := copyRootProviders(root)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := newRootConfigImpl(func() ([]Provider, error) {
+		for _, provider := range base {
+			if err := provider.Load(); err != nil {
+				return nil, err
+			}
+		}
+		return resolveKeyVaultProviders(base, resolver)
+	})
+	resolved.derive = func(providers []Provider) []Provider {
+		if len(providers) == 0 {
+			return providers
+		}
+		// The references which fail now keep their raw values.
+		providers, _ = resolveKeyVaultProviders(providers[:len(providers)-1], resolver)
+		return providers
+	}
+	if r, ok := root.(*rootConfigImpl); ok {
+		resolved.legacyKeys = r.legacyKeys
+		resolved.setBuildErrors(r.getBuildErrors())
+	}
+
+	providers, err := resolveKeyVaultProviders(base, resolver)
+	resolved.setProviders(providers)
+	return resolved, err
+}
+
+// copyRootProviders returns copies of the providers of the root, so they can be
+// reloaded without changing the root. Other implementations of
+// [config.RootConfig] are used as a single layer.
+func copyRootProviders(root RootConfig) ([]Provider, error) {
+	r, ok := root.(*rootConfigImpl)
+	if !ok {
+		return []Provider{newLoadedConfigProvider(&rootConfigLayer{root})}, nil
+	}
+
+	var providers []Provider
+	for _, provider := range r.getProviders() {
+		copied, err := copyProvider(provider)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, copied)
+	}
+	return providers, nil
+}
+
+// resolveKeyVaultProviders returns the providers with the layer of resolved
+// references on top.
+func resolveKeyVaultProviders(providers []Provider, resolver KeyVaultResolver) ([]Provider, error) {
+	layer := newKeyVaultConfig(resolver)
+	snapshot := newRootSnapshot(providers)
+
+	var errs KeyVaultReferenceErrors
+	for _, name := range snapshot.keys {
+		entry := snapshot.entries[normalizeKey(name)]
+		if entry == nil || !IsKeyVaultReference(entry.Value()) {
+			continue
+		}
+
+		ref, err := ParseKeyVaultReference(entry.Value())
+		value := ""
+		if err == nil {
+			value, err = resolver.Resolve(ref)
+		}
+
+		if err != nil {
+			errs = append(errs, &KeyVaultReferenceError{
+				Key:       entry.Key(),
+				Reference: entry.Value(),
+				Source:    entry.Source(),
+				Err:       err,
+			})
+			continue
+		}
+
+		layer.entries[normalizeKey(entry.Key())] = &keyVaultEntryImpl{
+			Entry:     entry,
+			value:     value,
+			reference: ref,
+			resolver:  resolver.Name(),
+		}
+	}
+
+	resolved := make([]Provider, 0, len(providers)+1)
+	resolved = append(resolved, providers...)
+	resolved = append(resolved, newLoadedConfigProvider(layer))
+
+	if len(errs) > 0 {
+		return resolved, errs
+	}
+	return resolved, nil
+}
+
+// keyVaultSource is the [config.Source] of the resolved Key Vault references.
+type keyVaultSource struct {
+	config *keyVaultConfig
+}
+
+func (s *keyVaultSource) Name() string {
+	return fmt.Sprintf("KeyVault: %s", s.config.resolver.Name())
+}
+
+func (s *keyVaultSource) Build() (Config, error) {
+	return s.config, nil
+}
+
+// keyVaultConfig implements [config.Config] with the resolved Key Vault references.
+type keyVaultConfig struct {
+	resolver KeyVaultResolver
+	entries  map[string]*keyVaultEntryImpl
+}
+
+func newKeyVaultConfig(resolver KeyVaultResolver) *keyVaultConfig {
+	return &keyVaultConfig{
+		resolver: resolver,
+		entries:  make(map[string]*keyVaultEntryImpl),
+	}
+}
+
+func (c *keyVaultConfig) Get(key string) string {
+	val := ""
+	c.TryGet(key, &val)
+	return val
+}
+
+func (c *keyVaultConfig) TryGet(key string, val *string) (found bool) {
+	entry, found := c.entries[normalizeKey(key)]
+	*val = ""
+	if found {
+		*val = entry.Value()
+	}
+	return found
+}
+
+func (c *keyVaultConfig) Keys() []string {
+	var keys []string
+	for _, entry := range c.entries {
+		keys = append(keys, entry.Key())
+	}
+
+	sortKeys(keys)
+	return keys
+}
+
+func (c *keyVaultConfig) Source() Source {
+	return &keyVaultSource{config: c}
+}
+
+func (c *keyVaultConfig) tryGetEntry(key string) (Entry, bool) {
+	entry, found := c.entries[normalizeKey(key)]
+	if !found {
+		return newEntryImpl(key, "", c.Source()), false
+	}
+	return entry, true
+}
+
+// keyVaultReferenceConfig marks the values of some keys of the wrapped config
+// as resolved Key Vault references, like [config.secretConfig] does for secrets.
+type keyVaultReferenceConfig struct {
+	Config
+	references map[string]KeyVaultReference
+	resolver   string
+}
+
+// newKeyVaultReferenceConfig creates [config.Config] where the entries with
+// normalised keys in references implement [config.KeyVaultEntry].
+func newKeyVaultReferenceConfig(config Config, references map[string]KeyVaultReference, resolver string) *keyVaultReferenceConfig {
+	return &keyVaultReferenceConfig{
+		Config:     config,
+		references: references,
+		resolver:   resolver,
+	}
+}
+
+func (c *keyVaultReferenceConfig) tryGetEntry(key string) (Entry, bool) {
+	entry, found := getConfigEntry(c.Config, key)
+	ref, isReference := c.references[normalizeKey(key)]
+	if !found || !isReference {
+		return entry, found
+	}
+	return &keyVaultEntryImpl{
+		Entry:     entry,
+		value:     entry.Value(),
+		reference: ref,
+		resolver:  c.resolver,
+	}, true
+}
+
+// rootConfigLayer allows to use any [config.RootConfig] as a layer of another
+// one without losing the sources of its entries.
+type rootConfigLayer struct {
+	RootConfig
+}
+
+func (c *rootConfigLayer) tryGetEntry(key string) (Entry, bool) {
+	val := ""
+	if found := c.RootConfig.TryGet(key, &val); !found {
+		return newEntryImpl(key, "", nil), false
+	}
+	return c.RootConfig.GetEntry(key), true
+}
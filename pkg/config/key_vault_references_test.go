@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseKeyVaultReference_SecretUri(t *testing.T) {
+	ref, err := ParseKeyVaultReference("@Microsoft.KeyVault(SecretUri=https://v.vault.azure.net/secrets/db/abc)")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://v.vault.azure.net", ref.VaultURI)
+	assert.Equal(t, "v", ref.VaultName)
+	assert.Equal(t, "db", ref.SecretName)
+	assert.Equal(t, "abc", ref.SecretVersion)
+
+	ref, err = ParseKeyVaultReference("@Microsoft.KeyVault(secreturi=https://v.vault.azure.net/secrets/db/)")
+	assert.NoError(t, err)
+	assert.Equal(t, "db", ref.SecretName)
+	assert.Equal(t, "", ref.SecretVersion)
+}
+
+func Test_ParseKeyVaultReference_VaultName(t *testing.T) {
+	ref, err := ParseKeyVaultReference("@Microsoft.KeyVault(VaultName=v;SecretName=db)")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://v.vault.azure.net", ref.VaultURI)
+	assert.Equal(t, "v", ref.VaultName)
+	assert.Equal(t, "db", ref.SecretName)
+	assert.Equal(t, "", ref.SecretVersion)
+
+	ref, err = ParseKeyVaultReference("@Microsoft.KeyVault(VaultName=v; SecretName=db; SecretVersion=abc)")
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", ref.SecretVersion)
+}
+
+func Test_ParseKeyVaultReference_Invalid(t *testing.T) {
+	invalid := []string{
+		"plain value",
+		"@Microsoft.KeyVault()",
+		"@Microsoft.KeyVault(VaultName=v)",
+		"@Microsoft.KeyVault(VaultName=v;SecretName=db;Foo=bar)",
+		"@Microsoft.KeyVault(SecretUri=http://v.vault.azure.net/secrets/db)",
+		"@Microsoft.KeyVault(SecretUri=https://v.vault.azure.net/keys/db)",
+		"@Microsoft.KeyVault(SecretUri=https://v.vault.azure.net/secrets/db;SecretName=db)",
+	}
+
+	for _, value := range invalid {
+		_, err := ParseKeyVaultReference(value)
+		assert.Errorf(t, err, "value: %s", value)
+	}
+}
+
+func Test_ResolveKeyVaultReferences(t *testing.T) {
+	env := map[string]string{
+		"ConnectionStrings__Sql": "@Microsoft.KeyVault(SecretUri=https://v.vault.azure.net/secrets/db/abc)",
+		"Redis":                  "@Microsoft.KeyVault(VaultName=V;SecretName=REDIS)",
+		"Missing":                "@Microsoft.KeyVault(VaultName=v;SecretName=missing)",
+		"Broken":                 "@Microsoft.KeyVault(VaultName=v)",
+		"Plain":                  "plain value",
+	}
+
+	builder := NewBuilder()
+	builder.AddSource(NewEnvVarsMapSource("", env).WithName("env"))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	resolver := NewKeyVaultMapResolver(map[string]map[string]string{
+		"v": {
+			"db":     "latest db",
+			"db/abc": "db version abc",
+			"redis":  "redis value",
+		},
+	})
+
+	resolved, err := ResolveKeyVaultReferences(root, resolver)
+
+	var errs KeyVaultReferenceErrors
+	if assert.True(t, errors.As(err, &errs)) {
+		assert.Len(t, errs, 2)
+		assert.Equal(t, "Broken", errs[0].Key)
+		assert.Equal(t, "env", errs[0].Source.Name())
+		assert.Equal(t, "Missing", errs[1].Key)
+		assert.True(t, errors.Is(errs[1], ErrKeyVaultSecretNotFound))
+	}
+
+	assert.Equal(t, "db version abc", resolved.Get("ConnectionStrings:Sql"))
+	assert.Equal(t, "redis value", resolved.Get("redis"))
+	assert.Equal(t, "plain value", resolved.Get("plain"))
+	assert.Equal(t, env["Missing"], resolved.Get("missing"))
+
+	// original is not modified
+	assert.Equal(t, env["Redis"], root.Get("redis"))
+
+	entry, ok := resolved.GetEntry("redis").(KeyVaultEntry)
+	if assert.True(t, ok) {
+		assert.Equal(t, "env", entry.Source().Name())
+		assert.Equal(t, env["Redis"], entry.Reference().Raw)
+		assert.Equal(t, "https://V.vault.azure.net", entry.Vault())
+		assert.Equal(t, "KeyVaultMapResolver", entry.Resolver())
+	}
+
+	var kvEntries int
+	for _, entry := range resolved.GetEntries() {
+		if _, ok := entry.(KeyVaultEntry); ok {
+			kvEntries++
+		}
+	}
+	assert.Equal(t, 2, kvEntries)
+}
+
+func Test_KeyVaultFileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	err := os.WriteFile(path, []byte(`{"v": {"db": "db from file"}}`), 0600)
+	assert.NoError(t, err)
+
+	resolver := NewKeyVaultFileResolver(path)
+	value, err := resolver.Resolve(KeyVaultReference{VaultName: "v", SecretName: "DB", SecretVersion: "abc"})
+	assert.NoError(t, err)
+	assert.Equal(t, "db from file", value)
+
+	_, err = resolver.Resolve(KeyVaultReference{VaultName: "other", SecretName: "db"})
+	assert.True(t, errors.Is(err, ErrKeyVaultSecretNotFound))
+
+	_, err = NewKeyVaultFileResolver(filepath.Join(t.TempDir(), "missing.json")).Resolve(KeyVaultReference{})
+	assert.Error(t, err)
+}
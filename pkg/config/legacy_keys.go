@@ -0,0 +1,144 @@
+package config
+
+// NewLegacyKeySource creates a source which converts double underscore "__" in
+// all keys of the wrapped source to the key delimiter ":", as this package did
+// for all sources before. By default, like in ASP.NET, only environment
+// variables have "__" converted, so Json property "a__b" stays "a__b".
+//
+// The keys are converted once when the source is built. To convert the keys of
+// all sources, and "__" in the lookups too, see [config.BuildOptions].
+func NewLegacyKeySource(source Source) *LegacyKeySource {
+	return &LegacyKeySource{
+		source: source,
+	}
+}
+
+// LegacyKeySource implements [config.Source] interface.
+type LegacyKeySource struct {
+	source Source
+}
+
+// Source returns the wrapped source.
+func (s *LegacyKeySource) Source() Source {
+	return s.source
+}
+
+// Name is the name of the wrapped source. Part of [config.Source] interface.
+func (s *LegacyKeySource) Name() string {
+	return s.source.Name()
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *LegacyKeySource) Build() (Config, error) {
+	return s.BuildWithDefaults(nil)
+}
+
+// BuildWithDefaults passes the builder properties to the wrapped source.
+// Part of [config.DefaultsSource] interface.
+func (s *LegacyKeySource) BuildWithDefaults(properties map[string]interface{}) (Config, error) {
+	config, err := buildWithDefaults(s.source, properties)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := config.(*missingConfig); ok {
+		// Keep the optional source which does not exist visible to Diagnostics.
+		return config, nil
+	}
+	return newLegacyKeyConfig(config), nil
+}
+
+// legacyKeyConfig is the config of [config.LegacyKeySource].
+type legacyKeyConfig struct {
+	config Config
+	// keys are the converted keys, sorted.
+	keys []string
+	// index maps normalised converted keys to the keys of the wrapped config.
+	index map[string]string
+}
+
+func newLegacyKeyConfig(config Config) *legacyKeyConfig {
+	c := &legacyKeyConfig{
+		config: config,
+		index:  make(map[string]string),
+	}
+	for _, key := range config.Keys() {
+		name := envKeyName(key)
+		normalized := normalizeKey(name)
+		if _, found := c.index[normalized]; !found {
+			c.keys = append(c.keys, name)
+		}
+		c.index[normalized] = key
+	}
+
+	sortKeys(c.keys)
+	return c
+}
+
+func (c *legacyKeyConfig) Get(key string) string {
+	val := ""
+	c.TryGet(key, &val)
+	return val
+}
+
+func (c *legacyKeyConfig) TryGet(key string, val *string) (found bool) {
+	entry, found := c.tryGetEntry(key)
+	*val = entry.Value()
+	return found
+}
+
+func (c *legacyKeyConfig) Keys() []string {
+	keys := make([]string, len(c.keys))
+	copy(keys, c.keys)
+	return keys
+}
+
+func (c *legacyKeyConfig) Source() Source {
+	return c.config.Source()
+}
+
+func (c *legacyKeyConfig) tryGetEntry(key string) (Entry, bool) {
+	name := envKeyName(key)
+	originalKey, found := c.index[normalizeKey(name)]
+	if !found {
+		return newEntryImpl(key, "", c.Source()), false
+	}
+
+	entry, found := getConfigEntry(c.config, originalKey)
+	return withKey(entry, name), found
+}
+
+// withKey returns the entry with another key, keeping the additional
+// information of the entry, e.g. the location of [config.LocatedEntry].
+func withKey(entry Entry, key string) Entry {
+	switch e := entry.(type) {
+	case *configEntryImpl:
+		return newEntryImpl(key, e.value, e.configSource)
+	case *secretEntryImpl:
+		return &secretEntryImpl{Entry: withKey(e.Entry, key)}
+	case *locatedEntryImpl:
+		return &locatedEntryImpl{Entry: withKey(e.Entry, key), location: e.location}
+	case *jsonEntryImpl:
+		located := &locatedEntryImpl{Entry: withKey(e.locatedEntryImpl.Entry, key), location: e.location}
+		return &jsonEntryImpl{locatedEntryImpl: located, kind: e.kind}
+	case *secretJsonEntryImpl:
+		return &secretJsonEntryImpl{withKey(e.jsonEntryImpl, key).(*jsonEntryImpl)}
+	case *httpJsonEntryImpl:
+		return &httpJsonEntryImpl{jsonEntryImpl: withKey(e.jsonEntryImpl, key).(*jsonEntryImpl), config: e.config}
+	case *keyVaultEntryImpl:
+		renamed := *e
+		renamed.Entry = withKey(e.Entry, key)
+		return &renamed
+	default:
+		return &keyedEntry{Entry: entry, key: key}
+	}
+}
+
+// keyedEntry replaces the key of an entry of unknown type.
+type keyedEntry struct {
+	Entry
+	key string
+}
+
+func (e *keyedEntry) Key() string {
+	return e.key
+}
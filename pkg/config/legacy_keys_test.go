@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LegacyKeyNormalization(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"a__b": "json", "Logging__Level": "Info"}`)))
+	root, err := builder.BuildWithOptions(BuildOptions{LegacyKeyNormalization: true})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"a:b", "Logging:Level"}, root.Keys())
+	assert.Equal(t, "json", root.Get("a:b"))
+	assert.Equal(t, "json", root.Get("A__B"))
+	assert.Equal(t, []string{"Level"}, root.GetChildKeys("logging"))
+	assert.Equal(t, "Logging:Level", root.GetEntryChain("logging__level").Key)
+
+	// Json entries keep their location and kind.
+	entry, ok := root.GetEntry("Logging:Level").(JsonEntry)
+	if assert.True(t, ok) {
+		assert.Equal(t, "Logging:Level", entry.Key())
+		assert.Equal(t, JsonValueKindString, entry.JsonKind())
+		assert.Equal(t, 1, entry.Location().Line)
+	}
+
+	// The policy is decided when the configuration is built, other
+	// configurations are not affected.
+	other, err := builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a__b", "Logging__Level"}, other.Keys())
+	assert.False(t, other.TryGet("a:b", new(string)))
+	assert.Equal(t, "json", root.Get("a:b"))
+}
+
+func Test_LegacyKeySource(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewLegacyKeySource(NewJsonSource([]byte(`{"a__b": "legacy"}`))))
+	builder.AddSource(NewJsonSource([]byte(`{"c__d": "json"}`)))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"a:b", "c__d"}, root.Keys())
+	assert.Equal(t, "legacy", root.Get("A:B"))
+	assert.False(t, root.TryGet("a__b", new(string)))
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+)
+
+// Location is where a value is defined inside its source, e.g. the line and
+// column in a Json file, or the name of environment variable.
+type Location struct {
+	// File is the path of the file, empty when the source is not a file.
+	File string
+	// Line is 1-based line of the value in the file, zero when not known.
+	Line int
+	// Column is 1-based column of the value in the line, in bytes.
+	Column int
+	// Variable is the name of environment variable as it was spelled before
+	// it was rewritten into the key, e.g. "CONNECTIONSTRINGS__SQL" or
+	// "SQLAZURECONNSTR_Sql".
+	Variable string
+}
+
+// String renders the location for reports and editors, e.g.
+// "appsettings.json:12:7" or "CONNECTIONSTRINGS__SQL".
+func (l Location) String() string {
+	switch {
+	case l.Variable != "":
+		return l.Variable
+	case l.Line > 0 && l.File != "":
+		return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+	case l.Line > 0:
+		return fmt.Sprintf("%d:%d", l.Line, l.Column)
+	default:
+		return l.File
+	}
+}
+
+// LocatedEntry is implemented by entries which know where their values are
+// defined inside the source, e.g. values from Json files and environment
+// variables.
+type LocatedEntry interface {
+	Entry
+	// Location returns where the value is defined.
+	Location() Location
+}
+
+// locatedEntryImpl implements [config.LocatedEntry] interface.
+type locatedEntryImpl struct {
+	Entry
+	location Location
+}
+
+func (e *locatedEntryImpl) Location() Location {
+	return e.location
+}
+
+// locatedConfig adds the locations of the values to the wrapped config, and
+// the kinds of the values if they come from Json.
+type locatedConfig struct {
+	Config
+	locations map[string]Location
+	kinds     map[string]JsonValueKind
+}
+
+// newLocatedConfig creates [config.Config] where the entries with normalised
+// keys in locations implement [config.LocatedEntry].
+func newLocatedConfig(config Config, locations map[string]Location) *locatedConfig {
+	return &locatedConfig{
+		Config:    config,
+		locations: locations,
+	}
+}
+
+func (c *locatedConfig) tryGetEntry(key string) (Entry, bool) {
+	entry, found := getConfigEntry(c.Config, key)
+	if !found {
+		return entry, false
+	}
+	normalized := normalizeKey(key)
+	location, ok := c.locations[normalized]
+	if !ok {
+		return entry, true
+	}
+
+	located := &locatedEntryImpl{Entry: entry, location: location}
+	if kind, ok := c.kinds[normalized]; ok {
+		return &jsonEntryImpl{locatedEntryImpl: located, kind: kind}, true
+	}
+	return located, true
+}
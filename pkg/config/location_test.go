@@ -0,0 +1,105 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func entryLocation(t *testing.T, root RootConfig, key string) Location {
+	entry, ok := root.GetEntry(key).(LocatedEntry)
+	if !assert.Truef(t, ok, "entry %s has no location", key) {
+		return Location{}
+	}
+	return entry.Location()
+}
+
+func Test_Location_Json(t *testing.T) {
+	fsys := fstest.MapFS{
+		"appsettings.json": {Data: []byte(`{
+  // comment lines keep their place
+  "Logging": {
+    "LogLevel": { "Default": "Information" }
+  },
+  "Hosts": ["a",
+    "b"],
+  "Empty": {},
+  "Nothing": null
+}`)},
+	}
+
+	builder := NewBuilder()
+	builder.AddSource(NewJsonFileSource(fsys, "appsettings.json"))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	location := entryLocation(t, root, "Logging:LogLevel:Default")
+	assert.Equal(t, Location{File: "appsettings.json", Line: 4, Column: 30}, location)
+	assert.Equal(t, "appsettings.json:4:30", location.String())
+	assert.Equal(t, "appsettings.json:6:13", entryLocation(t, root, "Hosts:0").String())
+	assert.Equal(t, "appsettings.json:7:5", entryLocation(t, root, "Hosts:1").String())
+	assert.Equal(t, "appsettings.json:8:12", entryLocation(t, root, "Empty").String())
+	assert.Equal(t, "appsettings.json:9:14", entryLocation(t, root, "Nothing").String())
+
+	// Json from bytes has no file.
+	builder = NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"a": 1}`)))
+	root, err = builder.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "1:7", entryLocation(t, root, "a").String())
+}
+
+func Test_Location_Json_Errors(t *testing.T) {
+	for _, json := range []string{
+		``,
+		`{"a": 1} {}`,
+		`{"a": 1, "a": 2}`,
+		`{"a": {"b": 1}, "a:b": 2}`,
+	} {
+		_, err := NewJsonSource([]byte(json)).Build()
+		assert.Error(t, err, json)
+	}
+}
+
+func Test_Location_EnvVars(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewEnvVarsMapSource("", map[string]string{
+		"CONNECTIONSTRINGS__SQL": "Server=a",
+		"SQLAZURECONNSTR_Azure":  "Server=b",
+	}))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, Location{Variable: "CONNECTIONSTRINGS__SQL"}, entryLocation(t, root, "ConnectionStrings:Sql"))
+	assert.Equal(t, "SQLAZURECONNSTR_Azure", entryLocation(t, root, "ConnectionStrings:Azure").String())
+	assert.Equal(t, "SQLAZURECONNSTR_Azure", entryLocation(t, root, "ConnectionStrings:Azure_ProviderName").String())
+}
+
+func Test_Location_FileSource(t *testing.T) {
+	registerTestFormat(t)
+	fsys := fstest.MapFS{
+		"appsettings.json": {Data: []byte("{\n  \"a\": \"json\"\n}")},
+		"app.kv":           {Data: []byte("b=kv\n")},
+	}
+
+	builder := NewBuilder()
+	builder.Properties()[PropertyFileSystem] = fsys
+	builder.AddSource(NewFileSource("appsettings.json"))
+	builder.AddSource(NewFileSource("app.kv"))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "appsettings.json:2:8", entryLocation(t, root, "a").String())
+	assert.Equal(t, Location{File: "app.kv"}, entryLocation(t, root, "b"))
+
+	// Relative paths are reported relative to the base path.
+	dir := t.TempDir()
+	builder = NewBuilder()
+	builder.Properties()[PropertyBasePath] = dir
+	builder.AddSource(NewFileSource("missing.json"))
+	_, err = builder.Build()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "missing.json"))
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// NewManager creates new empty [config.Manager].
+func NewManager() *Manager {
+	m := &Manager{}
+	m.rootConfigImpl = newRootConfigImpl(func() ([]Provider, error) {
+		return loadProviders(m.Sources(), nil, false)
+	})
+	m.setProviders(nil)
+	return m
+}
+
+// Manager is a mutable builder and live [config.RootConfig] in one object,
+// similar to ASP.NET ConfigurationManager. Sources added to the Manager are
+// visible right away, so the startup code can read early settings, like the
+// environment name, to decide which further sources to add.
+//
+// Manager is safe for concurrent use. Adding and removing sources replaces
+// the providers at once, and reports the changes with OnChange.
+type Manager struct {
+	*rootConfigImpl
+
+	mu      sync.RWMutex
+	sources []Source
+}
+
+// AddSource builds and loads the source, and adds it to the end of the list
+// of sources. The source takes precedence over the sources added earlier.
+// On error the source is not added.
+func (m *Manager) AddSource(source Source) error {
+	m.reloadMu.Lock()
+	m.setReloading(true)
+
+	provider, err := buildProvider(source, nil)
+	if err == nil {
+		err = provider.Load()
+	}
+
+	var changes []ConfigChange
+	if err == nil {
+		m.mu.Lock()
+		m.sources = append(m.sources, source)
+		m.mu.Unlock()
+
+		providers := append(m.Providers(), provider)
+		changes = m.setProviders(providers)
+	}
+
+	m.setReloading(false)
+	m.reloadMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	m.reloadToken.onReload()
+	m.notify(changes)
+	return nil
+}
+
+// RemoveSource removes all sources with the name, and reports whether any
+// source was removed.
+func (m *Manager) RemoveSource(name string) bool {
+	// The providers are in the same order as the sources only while reloadMu
+	// is held, because AddSource and Reload replace both under it.
+	m.reloadMu.Lock()
+
+	m.mu.Lock()
+	current := m.getProviders()
+	var sources []Source
+	var providers []Provider
+	for i, source := range m.sources {
+		if source.Name() != name {
+			sources = append(sources, source)
+			providers = append(providers, current[i])
+		}
+	}
+	removed := len(sources) != len(m.sources)
+	m.sources = sources
+	m.mu.Unlock()
+
+	var changes []ConfigChange
+	if removed {
+		changes = m.setProviders(providers)
+	}
+
+	m.reloadMu.Unlock()
+
+	if removed {
+		m.reloadToken.onReload()
+		m.notify(changes)
+	}
+	return removed
+}
+
+// Sources returns the sources in precedence order, i.e. the later sources
+// take precedence over earlier ones.
+func (m *Manager) Sources() []Source {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sources := make([]Source, len(m.sources))
+	copy(sources, m.sources)
+	return sources
+}
+
+// Set sets the value for the key in every provider, in the same way as
+// ASP.NET ConfigurationRoot does. The values are kept until the next Reload.
+// Like in ASP.NET, setting values does not invoke OnChange callbacks.
+func (m *Manager) Set(key string, value string) error {
+	// The providers must not be replaced until the value is published.
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	providers := m.getProviders()
+	if len(providers) == 0 {
+		return errors.New("Manager: no configuration sources are added")
+	}
+
+	for _, provider := range providers {
+		provider.Set(key, value)
+	}
+	m.refreshLocked()
+	return nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Manager(t *testing.T) {
+	var root RootConfig = NewManager()
+	manager := root.(*Manager)
+	assert.Empty(t, manager.Keys())
+	assert.Error(t, manager.Set("a", "b"))
+
+	var changes []ConfigChange
+	manager.OnChange(func(c []ConfigChange) { changes = c })
+
+	assert.NoError(t, manager.AddSource(NewEnvVarsMapSource("", map[string]string{
+		"ENVIRONMENT": "Staging",
+	}).WithName("env")))
+	assert.Equal(t, "Staging", manager.Get("Environment"))
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, ConfigChangeAdded, changes[0].Kind)
+	}
+
+	// Early settings decide which sources to add.
+	json := fmt.Sprintf(`{"Environment": "Overridden", "Name": "%s"}`, manager.Get("Environment"))
+	assert.NoError(t, manager.AddSource(NewJsonSource([]byte(json)).WithName("json")))
+	assert.Equal(t, "Overridden", manager.Get("Environment"))
+	assert.Equal(t, "Staging", manager.Get("Name"))
+	assert.Equal(t, "json", manager.GetEntry("Name").Source().Name())
+
+	sources := manager.Sources()
+	if assert.Len(t, sources, 2) {
+		assert.Equal(t, "env", sources[0].Name())
+		assert.Equal(t, "json", sources[1].Name())
+	}
+
+	// Failed source is not added.
+	assert.Error(t, manager.AddSource(NewJsonSource([]byte(`broken`))))
+	assert.Len(t, manager.Sources(), 2)
+	assert.Len(t, manager.Providers(), 2)
+
+	// Set writes to every provider.
+	assert.NoError(t, manager.Set("Name", "set"))
+	for _, provider := range manager.Providers() {
+		val := ""
+		assert.True(t, provider.TryGet("name", &val))
+		assert.Equal(t, "set", val)
+	}
+
+	// Reload builds the sources again, which drops the set values.
+	assert.NoError(t, manager.Reload())
+	assert.Equal(t, "Staging", manager.Get("Name"))
+
+	assert.True(t, manager.RemoveSource("json"))
+	assert.False(t, manager.RemoveSource("json"))
+	assert.Equal(t, "Staging", manager.Get("Environment"))
+	assert.Equal(t, "", manager.Get("Name"))
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, ConfigChangeModified, changes[0].Kind)
+		assert.Equal(t, ConfigChangeRemoved, changes[1].Kind)
+	}
+}
+
+func Test_Manager_Concurrent(t *testing.T) {
+	manager := NewManager()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			source := NewEnvVarsMapSource("", map[string]string{fmt.Sprintf("key%d", i): "value"})
+			assert.NoError(t, manager.AddSource(source.WithName(fmt.Sprintf("source%d", i))))
+			assert.NoError(t, manager.Reload())
+		}(i)
+		go func() {
+			defer wg.Done()
+			_ = manager.GetEntries()
+			_ = manager.GetChildKeys("")
+			_ = manager.Set("shared", "value")
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, manager.Sources(), 10)
+	assert.Len(t, manager.Providers(), 10)
+	assert.Equal(t, "value", manager.Get("key9"))
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Provider provides configuration keys and values from a source. This is
+// similar to ASP.NET IConfigurationProvider interface, and allows to reload
+// and to override the values, unlike immutable [config.Config].
+//
+// [config.RootConfig] aggregates providers built from sources, see
+// [config.ProviderSource]. The keys are case-insensitive and keep their
+// original casing in the same way as in [config.Config].
+type Provider interface {
+	// Load loads or reloads the values from the source.
+	Load() error
+	// TryGet returns a value for the specified key and an indicator whether it exists.
+	TryGet(key string, val *string) (found bool)
+	// Set sets a value for the key. The value is kept until the next Load.
+	// [config.RootConfig] reads from the snapshot of its providers, and does
+	// not see the value until it refreshes, see [config.Manager.Set].
+	Set(key string, value string)
+	// GetChildKeys returns the immediate child keys of the parent path provided
+	// by this provider, appended to the keys of earlier providers and sorted.
+	// An empty parent path means the top level keys.
+	GetChildKeys(earlierKeys []string, parentPath string) []string
+	// GetReloadToken returns a [config.ChangeToken] which changes when this
+	// provider reloads.
+	GetReloadToken() ChangeToken
+	// Keys lists all keys in this provider with their original casing. The list
+	// is in alphabetical order ignoring the casing.
+	// Note: this method is not part of .NET IConfigurationProvider.
+	Keys() []string
+	// Source returns the [config.Source] which built this Provider.
+	// Note: this method is not part of .NET IConfigurationProvider.
+	Source() Source
+}
+
+// ProviderSource is implemented by sources which build a [config.Provider]
+// directly. Other sources are used through [config.NewConfigProvider].
+type ProviderSource interface {
+	Source
+	// BuildProvider builds a Provider, which is then loaded by the builder.
+	BuildProvider() (Provider, error)
+}
+
+// buildProvider builds the provider for the source, without loading it. The
+// provider of [config.DefaultsSource] builds it with the builder properties.
+func buildProvider(source Source, properties map[string]interface{}) (Provider, error) {
+	if ps, ok := source.(ProviderSource); ok {
+		return ps.BuildProvider()
+	}
+	if ds, ok := source.(DefaultsSource); ok {
+		return &configProvider{
+			source:      source,
+			build:       func() (Config, error) { return ds.BuildWithDefaults(properties) },
+			reloadToken: newReloadToken(),
+		}, nil
+	}
+	return NewConfigProvider(source), nil
+}
+
+// NewConfigProvider creates [config.Provider] for the source which provides
+// immutable [config.Config]. Each Load builds the source again, and replaces
+// the values set with Set.
+func NewConfigProvider(source Source) Provider {
+	return &configProvider{
+		source:      source,
+		build:       source.Build,
+		reloadToken: newReloadToken(),
+	}
+}
+
+// newLoadedConfigProvider creates [config.Provider] for already built config.
+func newLoadedConfigProvider(config Config) Provider {
+	return &configProvider{
+		source:      config.Source(),
+		build:       func() (Config, error) { return config, nil },
+		config:      config,
+		reloadToken: newReloadToken(),
+	}
+}
+
+// copyProvider returns a provider with the current values of the provider,
+// which loads from the same source independently of it.
+func copyProvider(provider Provider) (Provider, error) {
+	if p, ok := provider.(*configProvider); ok {
+		return p.copy(), nil
+	}
+
+	copied, err := buildProvider(provider.Source(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return copied, copied.Load()
+}
+
+// configProvider implements [config.Provider] interface for [config.Config].
+type configProvider struct {
+	source      Source
+	build       func() (Config, error)
+	reloadToken *reloadToken
+
+	mu     sync.RWMutex
+	config Config
+	data   map[string]string
+	// dataNames are the keys of data with their original casing.
+	dataNames map[string]string
+}
+
+// copy returns the provider with the same build and the current values.
+func (p *configProvider) copy() *configProvider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	copied := &configProvider{
+		source:      p.source,
+		build:       p.build,
+		reloadToken: newReloadToken(),
+		config:      p.config,
+	}
+	if p.data != nil {
+		copied.data = make(map[string]string, len(p.data))
+		copied.dataNames = make(map[string]string, len(p.dataNames))
+		for key, value := range p.data {
+			copied.data[key] = value
+		}
+		for key, name := range p.dataNames {
+			copied.dataNames[key] = name
+		}
+	}
+	return copied
+}
+
+func (p *configProvider) Load() error {
+	config, err := p.build()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.config = config
+	p.data = nil
+	p.dataNames = nil
+	p.mu.Unlock()
+
+	p.reloadToken.onReload()
+	return nil
+}
+
+func (p *configProvider) TryGet(key string, val *string) (found bool) {
+	entry, found := p.tryGetEntry(key)
+	*val = entry.Value()
+	return found
+}
+
+func (p *configProvider) Set(key string, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.data == nil {
+		p.data = make(map[string]string)
+		p.dataNames = make(map[string]string)
+	}
+
+	normalized := normalizeKey(key)
+	p.data[normalized] = value
+	if _, found := p.dataNames[normalized]; !found {
+		p.dataNames[normalized] = key
+	}
+}
+
+func (p *configProvider) GetChildKeys(earlierKeys []string, parentPath string) []string {
+	prefix := ""
+	if parentPath != "" {
+		prefix = parentPath + keyDelimiter
+	}
+
+	keys := make([]string, 0, len(earlierKeys))
+	for _, key := range p.Keys() {
+		if hasPrefixFold(key, prefix) {
+			keys = append(keys, configKeySegment(key, len(prefix)))
+		}
+	}
+
+	keys = append(keys, earlierKeys...)
+	sort.SliceStable(keys, func(i int, j int) bool {
+		return compareConfigKeys(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+func (p *configProvider) GetReloadToken() ChangeToken {
+	return p.reloadToken.get()
+}
+
+func (p *configProvider) Keys() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	names := make(map[string]string, len(p.dataNames))
+	if p.config != nil {
+		for _, key := range p.config.Keys() {
+			names[normalizeKey(key)] = key
+		}
+	}
+	for normalized, key := range p.dataNames {
+		if _, found := names[normalized]; !found {
+			names[normalized] = key
+		}
+	}
+
+	keys := make([]string, 0, len(names))
+	for _, key := range names {
+		keys = append(keys, key)
+	}
+
+	sortKeys(keys)
+	return keys
+}
+
+func (p *configProvider) Source() Source {
+	return p.source
+}
+
+func (p *configProvider) tryGetEntry(key string) (Entry, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if val, found := p.data[normalizeKey(key)]; found {
+		return newEntryImpl(key, val, p.source), true
+	}
+	if p.config == nil {
+		return newEntryImpl(key, "", p.source), false
+	}
+	return getConfigEntry(p.config, key)
+}
+
+// getProviderEntry returns the entry for the key from the provider, preserving
+// any additional information provided by [config.entryConfig].
+func getProviderEntry(provider Provider, key string) (Entry, bool) {
+	if ec, ok := provider.(entryConfig); ok {
+		return ec.tryGetEntry(key)
+	}
+
+	val := ""
+	found := provider.TryGet(key, &val)
+	return newEntryImpl(key, val, provider.Source()), found
+}
+
+// configKeySegment returns the segment of the key which starts at prefixLength
+// and ends before the next delimiter.
+func configKeySegment(key string, prefixLength int) string {
+	segment := key[prefixLength:]
+	if i := strings.Index(segment, keyDelimiter); i >= 0 {
+		return segment[:i]
+	}
+	return segment
+}
+
+// compareConfigKeys compares keys in the same way as ASP.NET ConfigurationKeyComparer:
+// segment by segment, numeric segments by value and before other segments.
+func compareConfigKeys(x string, y string) int {
+	xParts := splitConfigKey(x)
+	yParts := splitConfigKey(y)
+
+	for i := 0; i < len(xParts) && i < len(yParts); i++ {
+		xValue, xErr := strconv.Atoi(xParts[i])
+		yValue, yErr := strconv.Atoi(yParts[i])
+
+		result := 0
+		switch {
+		case xErr == nil && yErr == nil:
+			result = xValue - yValue
+		case xErr == nil:
+			result = -1
+		case yErr == nil:
+			result = 1
+		default:
+			result = strings.Compare(strings.ToLower(xParts[i]), strings.ToLower(yParts[i]))
+		}
+
+		if result != 0 {
+			return result
+		}
+	}
+
+	return len(xParts) - len(yParts)
+}
+
+// splitConfigKey splits the key into segments, skipping empty ones.
+func splitConfigKey(key string) []string {
+	var parts []string
+	for _, part := range strings.Split(key, keyDelimiter) {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
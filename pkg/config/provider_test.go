@@ -0,0 +1,107 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryTestSource builds memoryTestProvider directly, like ASP.NET MemoryConfigurationSource.
+type memoryTestSource struct {
+	name string
+	data map[string]string
+}
+
+func (s *memoryTestSource) Name() string {
+	return s.name
+}
+
+func (s *memoryTestSource) Build() (Config, error) {
+	return newConfigImpl(s, s.data), nil
+}
+
+func (s *memoryTestSource) BuildProvider() (Provider, error) {
+	return &memoryTestProvider{Provider: NewConfigProvider(s)}, nil
+}
+
+type memoryTestProvider struct {
+	Provider
+	loads int
+}
+
+func (p *memoryTestProvider) Load() error {
+	p.loads++
+	return p.Provider.Load()
+}
+
+func Test_RootConfig_Providers(t *testing.T) {
+	memory := &memoryTestSource{name: "memory", data: map[string]string{"Hosts:10": "j", "Other": "o"}}
+
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"Hosts": ["a", "b", "c"], "Logging": {"Level": "Info"}}`)))
+	builder.AddSource(memory)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	providers := root.Providers()
+	if assert.Len(t, providers, 2) {
+		assert.Equal(t, "JsonSource", providers[0].Source().Name())
+		assert.Equal(t, "memory", providers[1].Source().Name())
+
+		provider, ok := providers[1].(*memoryTestProvider)
+		if assert.True(t, ok) {
+			assert.Equal(t, 1, provider.loads)
+		}
+	}
+
+	assert.Equal(t, []string{"Hosts", "Logging", "Other"}, root.GetChildKeys(""))
+	assert.Equal(t, []string{"0", "1", "2", "10"}, root.GetChildKeys("Hosts"))
+	assert.Equal(t, []string{"Level"}, root.GetChildKeys("LOGGING"))
+	assert.Empty(t, root.GetChildKeys("Missing"))
+
+	// Providers returns a copy.
+	providers[0] = nil
+	assert.NotNil(t, root.Providers()[0])
+}
+
+func Test_ConfigProvider_SetAndLoad(t *testing.T) {
+	provider := NewConfigProvider(NewJsonSource([]byte(`{"a": {"b": "json"}}`)))
+
+	val := ""
+	assert.False(t, provider.TryGet("a:b", &val))
+
+	assert.NoError(t, provider.Load())
+	assert.True(t, provider.TryGet("A:B", &val))
+	assert.Equal(t, "json", val)
+
+	provider.Set("A:B", "set")
+	provider.Set("a:c", "new")
+	assert.True(t, provider.TryGet("a:b", &val))
+	assert.Equal(t, "set", val)
+	assert.Equal(t, []string{"a:b", "a:c"}, provider.Keys())
+	assert.Equal(t, []string{"b", "c", "x"}, provider.GetChildKeys([]string{"x"}, "a"))
+
+	// Load replaces the values set with Set, and changes the reload token.
+	token := provider.GetReloadToken()
+	assert.NoError(t, provider.Load())
+	assert.True(t, token.HasChanged())
+	assert.False(t, provider.GetReloadToken().HasChanged())
+	assert.True(t, provider.TryGet("a:b", &val))
+	assert.Equal(t, "json", val)
+	assert.False(t, provider.TryGet("a:c", &val))
+}
+
+func Test_ConfigProvider_LoadError(t *testing.T) {
+	provider := NewConfigProvider(NewJsonSource([]byte(`not json`)))
+	token := provider.GetReloadToken()
+	assert.Error(t, provider.Load())
+	assert.False(t, token.HasChanged())
+}
+
+func Test_CompareConfigKeys(t *testing.T) {
+	assert.True(t, compareConfigKeys("a:2", "a:10") < 0)
+	assert.True(t, compareConfigKeys("a:10", "a:b") < 0)
+	assert.True(t, compareConfigKeys("a:B", "a:c") < 0)
+	assert.True(t, compareConfigKeys("a", "a:b") < 0)
+	assert.Equal(t, 0, compareConfigKeys("A:b", "a::B"))
+}
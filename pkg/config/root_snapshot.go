@@ -0,0 +1,188 @@
+package config
+
+import (
+	"sort"
+)
+
+// rootSnapshot is the immutable state of [config.RootConfig] at one point in
+// time. Reload replaces the whole snapshot at once, so readers never take
+// locks, and never see a mix of old and new values.
+type rootSnapshot struct {
+	// providers are the live providers in precedence order.
+	providers []Provider
+	// layers are the values of the providers when the snapshot was published,
+	// in the same order as providers. All reads are served from the layers.
+	layers []*frozenConfig
+	// entries are the effective entries by normalised keys.
+	entries map[string]Entry
+	// names are the distinct keys with the casing of the first provider which
+	// has the key, in the order the providers list them.
+	names []string
+	// keys are the names sorted.
+	keys []string
+	// optional are the optional sources which do not exist.
+	optional []BuildDiagnostic
+}
+
+// newRootSnapshot captures the current values of the providers.
+func newRootSnapshot(providers []Provider) *rootSnapshot {
+	providerKeys := make([][]string, len(providers))
+	firstNames := make(map[string]string)
+	var names []string
+	for i, provider := range providers {
+		providerKeys[i] = provider.Keys()
+		for _, key := range providerKeys[i] {
+			normalized := normalizeKey(key)
+			if _, found := firstNames[normalized]; !found {
+				firstNames[normalized] = key
+				names = append(names, key)
+			}
+		}
+	}
+
+	snapshot := &rootSnapshot{
+		providers: providers,
+		layers:    make([]*frozenConfig, 0, len(providers)),
+		entries:   make(map[string]Entry, len(names)),
+		names:     names,
+		keys:      make([]string, len(names)),
+	}
+	for i, provider := range providers {
+		layer := freezeProvider(provider, providerKeys[i], firstNames)
+		for _, key := range layer.keys {
+			normalized := normalizeKey(key)
+			if entry, found := layer.tryGetEntry(firstNames[normalized]); found {
+				snapshot.entries[normalized] = entry
+			}
+		}
+		snapshot.layers = append(snapshot.layers, layer)
+
+		if err := optionalSourceError(provider); err != nil {
+			snapshot.optional = append(snapshot.optional, BuildDiagnostic{Source: provider.Source(), Optional: true, Err: err})
+		}
+	}
+
+	copy(snapshot.keys, names)
+	sortKeys(snapshot.keys)
+	return snapshot
+}
+
+// getSnapshot returns the current snapshot without locking.
+func (c *rootConfigImpl) getSnapshot() *rootSnapshot {
+	return c.snapshot.Load().(*rootSnapshot)
+}
+
+// getChildKeys returns the distinct immediate child keys of the parent path,
+// with the casing of the first provider which has the key.
+func (s *rootSnapshot) getChildKeys(parentPath string) []string {
+	prefix := ""
+	if parentPath != "" {
+		prefix = parentPath + keyDelimiter
+	}
+
+	seen := make(map[string]bool)
+	var keys []string
+	for _, name := range s.names {
+		if !hasPrefixFold(name, prefix) {
+			continue
+		}
+		key := configKeySegment(name, len(prefix))
+		if normalized := normalizeKey(key); !seen[normalized] {
+			seen[normalized] = true
+			keys = append(keys, key)
+		}
+	}
+
+	sort.SliceStable(keys, func(i int, j int) bool {
+		return compareConfigKeys(keys[i], keys[j]) < 0
+	})
+	return keys
+}
+
+func (c *rootConfigImpl) Snapshot() RootConfig {
+	current := c.getSnapshot()
+	providers := make([]Provider, 0, len(current.layers))
+	for _, layer := range current.layers {
+		providers = append(providers, newLoadedConfigProvider(layer))
+	}
+
+	// The layers are never reloaded, so Reload keeps the same values.
+	snapshot := newRootConfigImpl(func() ([]Provider, error) {
+		return providers, nil
+	})
+	snapshot.legacyKeys = c.legacyKeys
+	diagnostics := append(BuildErrors(nil), c.getBuildErrors()...)
+	snapshot.setBuildErrors(append(diagnostics, current.optional...))
+	snapshot.setProviders(providers)
+	return snapshot
+}
+
+// freezeProvider copies the current values of the provider for the keys,
+// which the provider lists. The entries are also copied with the casing of
+// names by normalised keys, which is what [config.RootConfig] queries.
+func freezeProvider(provider Provider, keys []string, names map[string]string) *frozenConfig {
+	config := &frozenConfig{
+		source:  provider.Source(),
+		keys:    keys,
+		entries: make(map[string]Entry, len(keys)),
+		index:   make(map[string]string, len(keys)),
+	}
+	for _, key := range keys {
+		entry, found := getProviderEntry(provider, key)
+		if !found {
+			continue
+		}
+		config.entries[key] = entry
+		config.index[normalizeKey(key)] = key
+
+		if name, ok := names[normalizeKey(key)]; ok && name != key {
+			if entry, found := getProviderEntry(provider, name); found {
+				config.entries[name] = entry
+			}
+		}
+	}
+	return config
+}
+
+// frozenConfig implements [config.Config] with the entries copied from
+// a provider, keeping their additional information, e.g. locations.
+type frozenConfig struct {
+	source Source
+	keys   []string
+	// entries are the entries by the keys as they were queried.
+	entries map[string]Entry
+	// index maps normalised keys to the keys of the provider.
+	index map[string]string
+}
+
+func (c *frozenConfig) Get(key string) string {
+	val := ""
+	c.TryGet(key, &val)
+	return val
+}
+
+func (c *frozenConfig) TryGet(key string, val *string) (found bool) {
+	entry, found := c.tryGetEntry(key)
+	*val = entry.Value()
+	return found
+}
+
+func (c *frozenConfig) Keys() []string {
+	keys := make([]string, len(c.keys))
+	copy(keys, c.keys)
+	return keys
+}
+
+func (c *frozenConfig) Source() Source {
+	return c.source
+}
+
+func (c *frozenConfig) tryGetEntry(key string) (Entry, bool) {
+	if entry, found := c.entries[key]; found {
+		return entry, true
+	}
+	if providerKey, found := c.index[normalizeKey(key)]; found {
+		return c.entries[providerKey], true
+	}
+	return newEntryImpl(key, "", c.source), false
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RootConfig_Snapshot(t *testing.T) {
+	source := &mutableTestSource{name: "source", data: map[string]string{"a": "1", "Logging:Level": "Info"}}
+	builder := NewBuilder()
+	builder.AddSource(NewJsonSource([]byte(`{"a": "json", "b": "json"}`)).WithName("json"))
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	snapshot := root.Snapshot()
+
+	// Neither Reload nor the providers reloading by themselves change the snapshot.
+	source.set(map[string]string{"a": "2"}, nil)
+	assert.NoError(t, root.Reload())
+	source.set(map[string]string{"a": "3"}, nil)
+	assert.NoError(t, root.Providers()[1].Load())
+	assert.Equal(t, "3", root.Get("a"))
+	assert.Equal(t, "", root.Get("Logging:Level"))
+
+	assert.Equal(t, "1", snapshot.Get("a"))
+	assert.Equal(t, "Info", snapshot.Get("logging:level"))
+	assert.Equal(t, []string{"a", "b", "Logging:Level"}, snapshot.Keys())
+	assert.Equal(t, []string{"a", "b", "Logging"}, snapshot.GetChildKeys(""))
+
+	// The entries keep their sources and additional information.
+	chain := snapshot.GetEntryChain("a")
+	if assert.Len(t, chain.Layers, 2) {
+		assert.Equal(t, "json", chain.Layers[0].Source().Name())
+		_, located := chain.Layers[0].Entry.(LocatedEntry)
+		assert.True(t, located)
+		assert.True(t, chain.Layers[1].Winner)
+	}
+
+	assert.NoError(t, snapshot.Reload())
+	assert.Equal(t, "1", snapshot.Get("a"))
+
+	// Values set on a provider after the snapshot was published are not
+	// visible, neither in the root nor in new snapshots.
+	root.Providers()[1].Set("b", "set")
+	assert.Equal(t, "json", root.Get("b"))
+	assert.Equal(t, "json", root.Snapshot().Get("b"))
+	assert.Equal(t, "json", root.Snapshot().GetEntryChain("b").Winner().Value())
+}
+
+func Test_RootConfig_SnapshotDiagnostics(t *testing.T) {
+	builder := NewBuilder()
+	builder.AddSource(NewOptionalSource(NewJsonFileSource(fstest.MapFS{}, "missing.json")))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	assert.Len(t, root.Diagnostics(), 1)
+	assert.Equal(t, root.Diagnostics(), root.Snapshot().Diagnostics())
+}
+
+func Test_Manager_SetUpdatesSnapshot(t *testing.T) {
+	manager := NewManager()
+	assert.NoError(t, manager.AddSource(NewEnvVarsMapSource("", map[string]string{"a": "env"})))
+
+	snapshot := manager.Snapshot()
+	assert.NoError(t, manager.Set("a", "set"))
+	assert.Equal(t, "set", manager.Get("a"))
+	assert.Equal(t, "env", snapshot.Get("a"))
+}
+
+func Test_RootConfig_ConcurrentReloadAndRead(t *testing.T) {
+	first := &mutableTestSource{name: "first", data: map[string]string{"a": "0"}}
+	second := &mutableTestSource{name: "second", data: map[string]string{"b": "0"}}
+
+	builder := NewBuilder()
+	builder.AddSource(first)
+	builder.AddSource(second)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	var done int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&done) == 0 {
+				// The values of a snapshot always come from the same reload.
+				snapshot := root.Snapshot()
+				if a, b := snapshot.Get("a"), snapshot.Get("b"); strings.TrimSuffix(a, "+") != b {
+					t.Errorf("mixed layers: a=%s, b=%s", a, b)
+					return
+				}
+
+				val := ""
+				_ = root.TryGet("a", &val)
+				_ = root.GetEntry("b")
+				_ = root.Keys()
+				_ = root.GetChildKeys("")
+			}
+		}()
+	}
+
+	// Reload everything, and reload the providers by themselves in between.
+	for i := 1; i <= 100; i++ {
+		value := strconv.Itoa(i)
+		first.set(map[string]string{"a": value}, nil)
+		second.set(map[string]string{"b": value}, nil)
+		assert.NoError(t, root.Reload())
+
+		// The provider reloads by itself with a new value.
+		first.set(map[string]string{"a": value + "+"}, nil)
+		assert.NoError(t, root.Providers()[0].Load())
+		assert.Equal(t, value+"+", root.Get("a"))
+		assert.Equal(t, root.Get("a"), root.Snapshot().Get("a"))
+	}
+	atomic.StoreInt32(&done, 1)
+	wg.Wait()
+
+	assert.Equal(t, "100+", root.Get("a"))
+	assert.Equal(t, "100", root.Get("b"))
+}
@@ -0,0 +1,557 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sopsValueRegex matches SOPS encrypted values like
+// ENC[AES256_GCM,data:...,iv:...,tag:...,type:str].
+var sopsValueRegex = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// NewSopsJsonSource creates configuration source for Json encrypted with SOPS,
+// e.g. a committed appsettings.Production.json. Implements [config.Source].
+//
+// The values are decrypted in memory with the data key, or with the data key
+// which is decrypted using age identity file, see WithDataKey and
+// WithAgeIdentityFile.
+//
+// Age is not built in: with an identity file the data key is decrypted by
+// running the "age" command, which must be installed and on the PATH, see
+// https://github.com/FiloSottile/age. When it cannot be run or fails, Build
+// returns [config.AgeError]. The data key alone needs no external commands.
+//
+// The SOPS MAC is verified, and "unencrypted_suffix", "encrypted_suffix",
+// "unencrypted_regex" and "encrypted_regex" are honoured in the same way as
+// SOPS does.
+//
+// The decrypted Json is parsed in the same way as [config.JsonSource], and the
+// entries implement [config.JsonEntry] with the locations in the encrypted
+// Json. Entries with decrypted values also implement [config.SecretEntry].
+//
+// See: https://github.com/getsops/sops
+func NewSopsJsonSource(json []byte) *SopsJsonSource {
+	return &SopsJsonSource{
+		json: json,
+		name: "SopsJsonSource",
+	}
+}
+
+// SopsJsonSource implements [config.Source] interface.
+type SopsJsonSource struct {
+	json            []byte
+	name            string
+	dataKey         []byte
+	ageIdentityFile string
+}
+
+// sopsMetadata is the "sops" section of the encrypted file.
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `json:"recipient"`
+		Enc       string `json:"enc"`
+	} `json:"age"`
+	LastModified      string `json:"lastmodified"`
+	Mac               string `json:"mac"`
+	MacOnlyEncrypted  bool   `json:"mac_only_encrypted"`
+	UnencryptedSuffix string `json:"unencrypted_suffix"`
+	EncryptedSuffix   string `json:"encrypted_suffix"`
+	UnencryptedRegex  string `json:"unencrypted_regex"`
+	EncryptedRegex    string `json:"encrypted_regex"`
+	Version           string `json:"version"`
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *SopsJsonSource) WithName(name string) *SopsJsonSource {
+	s.name = name
+	return s
+}
+
+// WithDataKey sets the 256-bit data key used to decrypt the values and returns itself.
+func (s *SopsJsonSource) WithDataKey(key []byte) *SopsJsonSource {
+	s.dataKey = key
+	return s
+}
+
+// WithAgeIdentityFile sets the age identity file used to decrypt the data key
+// and returns itself. The data key is decrypted using the "age" command which
+// must be on the PATH, otherwise Build fails with [config.AgeError].
+func (s *SopsJsonSource) WithAgeIdentityFile(path string) *SopsJsonSource {
+	s.ageIdentityFile = path
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *SopsJsonSource) Name() string {
+	return s.name
+}
+
+// Build decrypts the values and builds Config. Part of [config.Source] interface.
+func (s *SopsJsonSource) Build() (Config, error) {
+	plain, d, err := s.decrypt()
+	if err != nil {
+		return nil, newSourceError("SopsJsonSource", s.name, "", err)
+	}
+
+	loader := newJsonLoader()
+	if _, err := loader.Load(bytes.NewReader(plain)); err != nil {
+		return nil, newSourceError("SopsJsonSource", s.name, "", err)
+	}
+
+	// The values are located in the encrypted Json, not in the decrypted one.
+	for key := range loader.positions {
+		if offset, ok := d.offsets[key]; ok {
+			line, column := jsonPosition(s.json, int(offset))
+			loader.positions[key] = Location{Line: line, Column: column}
+		}
+	}
+
+	return newSecretConfig(loader.config(s), d.secretKeys), nil
+}
+
+// decrypt returns decrypted Json without the "sops" section, and the decryptor
+// with the normalised keys of the values which were encrypted.
+func (s *SopsJsonSource) decrypt() ([]byte, *sopsDecryptor, error) {
+	dec := json.NewDecoder(bytes.NewReader(s.json))
+	dec.UseNumber()
+	root, err := decodeSopsNode(dec, s.json)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !root.isObject {
+		return nil, nil, errors.New("SOPS file must be a Json object")
+	}
+
+	var file struct {
+		Sops *sopsMetadata `json:"sops"`
+	}
+	if err := json.Unmarshal(s.json, &file); err != nil {
+		return nil, nil, err
+	}
+	if file.Sops == nil {
+		return nil, nil, errors.New("not a SOPS file, there is no 'sops' metadata")
+	}
+
+	key, err := s.getDataKey(file.Sops)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d, err := newSopsDecryptor(key, file.Sops)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := &bytes.Buffer{}
+	out.WriteString("{")
+	first := true
+	for _, field := range root.object {
+		if field.key == "sops" {
+			continue
+		}
+		if !first {
+			out.WriteString(",")
+		}
+		first = false
+		writeJsonString(out, field.key)
+		out.WriteString(":")
+		path := []string{field.key}
+		if err := d.walk(field.value, path, path, out); err != nil {
+			return nil, nil, err
+		}
+	}
+	out.WriteString("}")
+
+	if err := d.verifyMac(); err != nil {
+		return nil, nil, err
+	}
+
+	return out.Bytes(), d, nil
+}
+
+func (s *SopsJsonSource) getDataKey(meta *sopsMetadata) ([]byte, error) {
+	if s.dataKey != nil {
+		if len(s.dataKey) != 32 {
+			return nil, errors.Errorf("data key must be 32 bytes, got %d", len(s.dataKey))
+		}
+		return s.dataKey, nil
+	}
+
+	if s.ageIdentityFile == "" {
+		return nil, errors.New("no data key or age identity file supplied")
+	}
+
+	if len(meta.Age) == 0 {
+		return nil, errors.New("the file is not encrypted for any age recipient")
+	}
+
+	path, err := exec.LookPath("age")
+	if err != nil {
+		return nil, &AgeError{Err: err}
+	}
+
+	var lastErr error
+	for _, recipient := range meta.Age {
+		cmd := exec.Command(path, "--decrypt", "--identity", s.ageIdentityFile)
+		cmd.Stdin = strings.NewReader(recipient.Enc)
+		stderr := &bytes.Buffer{}
+		cmd.Stderr = stderr
+		key, err := cmd.Output()
+		if err == nil {
+			return key, nil
+		}
+		lastErr = &AgeError{Recipient: recipient.Recipient, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	}
+
+	return nil, lastErr
+}
+
+// AgeError describes the failure to decrypt the data key of
+// [config.SopsJsonSource] with the "age" command. When the command is not
+// installed, Err is exec.ErrNotFound, which can be checked with errors.Is.
+type AgeError struct {
+	// Recipient is the age recipient of the data key which failed, empty
+	// when the command could not be found.
+	Recipient string
+	// Stderr is the error output of the command.
+	Stderr string
+	// Err is the reason the command failed.
+	Err error
+}
+
+func (e *AgeError) Error() string {
+	if e.Recipient == "" {
+		return fmt.Sprintf("cannot decrypt data key, the age command is required: %v", e.Err)
+	}
+	if e.Stderr == "" {
+		return fmt.Sprintf("cannot decrypt data key for age recipient %s: %v", e.Recipient, e.Err)
+	}
+	return fmt.Sprintf("cannot decrypt data key for age recipient %s: %v: %s", e.Recipient, e.Err, e.Stderr)
+}
+
+func (e *AgeError) Unwrap() error {
+	return e.Err
+}
+
+// sopsDecryptor walks the tree in the same order as SOPS does, decrypting
+// values and computing the MAC.
+type sopsDecryptor struct {
+	key              []byte
+	meta             *sopsMetadata
+	hash             hash.Hash
+	encryptedRegex   *regexp.Regexp
+	unencryptedRegex *regexp.Regexp
+	secretKeys       map[string]bool
+	// offsets are the offsets of the values in the encrypted Json, by
+	// normalised key.
+	offsets map[string]int64
+}
+
+func newSopsDecryptor(key []byte, meta *sopsMetadata) (*sopsDecryptor, error) {
+	d := &sopsDecryptor{
+		key:        key,
+		meta:       meta,
+		hash:       sha512.New(),
+		secretKeys: make(map[string]bool),
+		offsets:    make(map[string]int64),
+	}
+
+	var err error
+	if meta.EncryptedRegex != "" {
+		if d.encryptedRegex, err = regexp.Compile(meta.EncryptedRegex); err != nil {
+			return nil, errors.Wrap(err, "invalid encrypted_regex")
+		}
+	}
+	if meta.UnencryptedRegex != "" {
+		if d.unencryptedRegex, err = regexp.Compile(meta.UnencryptedRegex); err != nil {
+			return nil, errors.Wrap(err, "invalid unencrypted_regex")
+		}
+	}
+	return d, nil
+}
+
+// walk writes the decrypted node to out. The path has only object keys and is
+// what SOPS uses for encryption, flatPath also has array indexes and gives the
+// configuration key.
+func (d *sopsDecryptor) walk(node *sopsNode, path []string, flatPath []string, out *bytes.Buffer) error {
+	d.offsets[normalizeKey(strings.Join(flatPath, keyDelimiter))] = node.offset
+
+	switch {
+	case node.isObject:
+		out.WriteString("{")
+		for i, field := range node.object {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			writeJsonString(out, field.key)
+			out.WriteString(":")
+			err := d.walk(field.value, appendPath(path, field.key), appendPath(flatPath, field.key), out)
+			if err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	case node.isArray:
+		out.WriteString("[")
+		for i, item := range node.array {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			err := d.walk(item, path, appendPath(flatPath, strconv.Itoa(i)), out)
+			if err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	default:
+		return d.walkValue(node.value, path, flatPath, out)
+	}
+	return nil
+}
+
+func (d *sopsDecryptor) walkValue(value interface{}, path []string, flatPath []string, out *bytes.Buffer) error {
+	str, isString := value.(string)
+	if !isString || !d.isEncrypted(path) {
+		if !d.meta.MacOnlyEncrypted {
+			d.hash.Write(sopsValueBytes(value))
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(b)
+		return nil
+	}
+
+	plaintext, valueType, err := d.decryptValue(str, strings.Join(path, ":")+":")
+	if err != nil {
+		return errors.Wrapf(err, "cannot decrypt '%s'", strings.Join(flatPath, keyDelimiter))
+	}
+
+	if valueType != "comment" {
+		d.hash.Write(plaintext)
+	}
+	d.secretKeys[normalizeKey(strings.Join(flatPath, keyDelimiter))] = true
+
+	switch valueType {
+	case "str", "bytes":
+		writeJsonString(out, string(plaintext))
+	case "int", "float":
+		if _, err := strconv.ParseFloat(string(plaintext), 64); err != nil {
+			return errors.Errorf("cannot decrypt '%s': invalid %s value", strings.Join(flatPath, keyDelimiter), valueType)
+		}
+		out.Write(plaintext)
+	case "bool":
+		b, err := strconv.ParseBool(string(plaintext))
+		if err != nil {
+			return errors.Errorf("cannot decrypt '%s': invalid bool value", strings.Join(flatPath, keyDelimiter))
+		}
+		out.WriteString(strconv.FormatBool(b))
+	default:
+		return errors.Errorf("cannot decrypt '%s': unsupported type '%s'", strings.Join(flatPath, keyDelimiter), valueType)
+	}
+	return nil
+}
+
+// isEncrypted decides whether the value under the path is encrypted, in the
+// same way as SOPS does.
+func (d *sopsDecryptor) isEncrypted(path []string) bool {
+	encrypted := true
+	if d.meta.UnencryptedSuffix != "" {
+		for _, p := range path {
+			if strings.HasSuffix(p, d.meta.UnencryptedSuffix) {
+				encrypted = false
+				break
+			}
+		}
+	}
+	if d.meta.EncryptedSuffix != "" {
+		encrypted = false
+		for _, p := range path {
+			if strings.HasSuffix(p, d.meta.EncryptedSuffix) {
+				encrypted = true
+				break
+			}
+		}
+	}
+	if d.unencryptedRegex != nil {
+		for _, p := range path {
+			if d.unencryptedRegex.MatchString(p) {
+				encrypted = false
+				break
+			}
+		}
+	}
+	if d.encryptedRegex != nil {
+		encrypted = false
+		for _, p := range path {
+			if d.encryptedRegex.MatchString(p) {
+				encrypted = true
+				break
+			}
+		}
+	}
+	return encrypted
+}
+
+// decryptValue decrypts the SOPS value with AES256-GCM using the additional data.
+func (d *sopsDecryptor) decryptValue(value string, additionalData string) ([]byte, string, error) {
+	match := sopsValueRegex.FindStringSubmatch(value)
+	if match == nil {
+		return nil, "", errors.New("value is not in SOPS format")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(match[1])
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid data")
+	}
+	iv, err := base64.StdEncoding.DecodeString(match[2])
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid iv")
+	}
+	tag, err := base64.StdEncoding.DecodeString(match[3])
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid tag")
+	}
+
+	block, err := aes.NewCipher(d.key)
+	if err != nil {
+		return nil, "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "wrong data key or corrupted value")
+	}
+	return plaintext, match[4], nil
+}
+
+// verifyMac checks the MAC of all values against the encrypted MAC in metadata.
+func (d *sopsDecryptor) verifyMac() error {
+	if d.meta.Mac == "" {
+		return errors.New("there is no MAC in SOPS metadata")
+	}
+
+	mac, _, err := d.decryptValue(d.meta.Mac, d.meta.LastModified)
+	if err != nil {
+		return errors.Wrap(err, "cannot decrypt MAC")
+	}
+
+	computed := fmt.Sprintf("%X", d.hash.Sum(nil))
+	if !strings.EqualFold(computed, string(mac)) {
+		return errors.New("MAC mismatch, the file was modified or the data key is wrong")
+	}
+	return nil
+}
+
+// sopsValueBytes converts the unencrypted Json value to bytes for MAC in the
+// same way SOPS does.
+func sopsValueBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case string:
+		return []byte(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return []byte(strconv.FormatInt(i, 10))
+		}
+		f, _ := v.Float64()
+		return []byte(strconv.FormatFloat(f, 'f', -1, 64))
+	case bool:
+		if v {
+			return []byte("True")
+		}
+		return []byte("False")
+	default:
+		return nil
+	}
+}
+
+func appendPath(path []string, elem string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, elem)
+}
+
+func writeJsonString(out *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	out.Write(b)
+}
+
+// sopsNode is a Json node which keeps the order of object properties, as
+// the order matters for SOPS MAC.
+type sopsNode struct {
+	isObject bool
+	isArray  bool
+	object   []sopsField
+	array    []*sopsNode
+	value    interface{}
+	// offset is the offset of the node in the Json.
+	offset int64
+}
+
+type sopsField struct {
+	key   string
+	value *sopsNode
+}
+
+func decodeSopsNode(dec *json.Decoder, input []byte) (*sopsNode, error) {
+	offset := jsonNextTokenOffset(input, dec.InputOffset())
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, isDelim := token.(json.Delim)
+	if !isDelim {
+		return &sopsNode{value: token, offset: offset}, nil
+	}
+
+	node := &sopsNode{offset: offset}
+	switch delim {
+	case '{':
+		node.isObject = true
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeSopsNode(dec, input)
+			if err != nil {
+				return nil, err
+			}
+			node.object = append(node.object, sopsField{key: keyToken.(string), value: value})
+		}
+	case '[':
+		node.isArray = true
+		for dec.More() {
+			value, err := decodeSopsNode(dec, input)
+			if err != nil {
+				return nil, err
+			}
+			node.array = append(node.array, value)
+		}
+	}
+
+	// closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sopsTestLastModified = "2024-01-01T00:00:00Z"
+
+var sopsTestKey = []byte("0123456789abcdef0123456789abcdef")
+
+// sopsTestEncrypt encrypts the value in the same way SOPS does.
+func sopsTestEncrypt(t *testing.T, plaintext string, valueType string, additionalData string) string {
+	block, err := aes.NewCipher(sopsTestKey)
+	assert.NoError(t, err)
+	iv := make([]byte, 32)
+	_, err = rand.Read(iv)
+	assert.NoError(t, err)
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	assert.NoError(t, err)
+
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+
+	enc := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]", enc(data), enc(iv), enc(tag), valueType)
+}
+
+// sopsTestMac computes encrypted MAC of the values in order.
+func sopsTestMac(t *testing.T, values ...string) string {
+	hash := sha512.New()
+	for _, v := range values {
+		hash.Write([]byte(v))
+	}
+	return sopsTestEncrypt(t, fmt.Sprintf("%X", hash.Sum(nil)), "str", sopsTestLastModified)
+}
+
+func sopsTestFile(t *testing.T, plainDescription string) []byte {
+	return []byte(fmt.Sprintf(`{
+	"ConnectionStrings": {
+		"Sql": %q
+	},
+	"Port": %q,
+	"Enabled": %q,
+	"Hosts": [%q, %q],
+	"Description_unencrypted": %q,
+	"sops": {
+		"age": [{"recipient": "age1test", "enc": "-----BEGIN AGE ENCRYPTED FILE-----"}],
+		"lastmodified": %q,
+		"mac": %q,
+		"unencrypted_suffix": "_unencrypted",
+		"version": "3.8.1"
+	}
+}`,
+		sopsTestEncrypt(t, "server=db", "str", "ConnectionStrings:Sql:"),
+		sopsTestEncrypt(t, "8080", "int", "Port:"),
+		sopsTestEncrypt(t, "True", "bool", "Enabled:"),
+		sopsTestEncrypt(t, "a", "str", "Hosts:"),
+		sopsTestEncrypt(t, "b", "str", "Hosts:"),
+		plainDescription,
+		sopsTestLastModified,
+		sopsTestMac(t, "server=db", "8080", "True", "a", "b", "plain"),
+	))
+}
+
+func Test_SopsJsonSource_DataKey(t *testing.T) {
+	config, err := NewSopsJsonSource(sopsTestFile(t, "plain")).WithDataKey(sopsTestKey).Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "server=db", config.Get("ConnectionStrings:Sql"))
+	assert.Equal(t, "8080", config.Get("Port"))
+	assert.Equal(t, "True", config.Get("Enabled"))
+	assert.Equal(t, "a", config.Get("Hosts:0"))
+	assert.Equal(t, "b", config.Get("Hosts:1"))
+	assert.Equal(t, "plain", config.Get("Description_unencrypted"))
+	assert.Equal(t, "", config.Get("sops:mac"))
+
+	builder := NewBuilder()
+	builder.AddSource(NewSopsJsonSource(sopsTestFile(t, "plain")).WithDataKey(sopsTestKey))
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	secret, ok := root.GetEntry("ConnectionStrings:Sql").(SecretEntry)
+	assert.True(t, ok && secret.Secret())
+	secret, ok = root.GetEntry("Hosts:1").(SecretEntry)
+	assert.True(t, ok && secret.Secret())
+	_, ok = root.GetEntry("Description_unencrypted").(SecretEntry)
+	assert.False(t, ok)
+}
+
+func Test_SopsJsonSource_MacMismatch(t *testing.T) {
+	_, err := NewSopsJsonSource(sopsTestFile(t, "tampered")).WithDataKey(sopsTestKey).Build()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "MAC mismatch")
+	}
+}
+
+func Test_SopsJsonSource_WrongKey(t *testing.T) {
+	_, err := NewSopsJsonSource(sopsTestFile(t, "plain")).WithDataKey([]byte("fedcba9876543210fedcba9876543210")).Build()
+	assert.Error(t, err)
+
+	_, err = NewSopsJsonSource(sopsTestFile(t, "plain")).Build()
+	assert.Error(t, err)
+
+	_, err = NewSopsJsonSource([]byte(`{"a": "b"}`)).WithDataKey(sopsTestKey).Build()
+	assert.Error(t, err)
+}
+
+func Test_SopsJsonSource_EncryptedRegex(t *testing.T) {
+	json := fmt.Sprintf(`{
+	"Db": {
+		"Host": "localhost",
+		"Password": %q
+	},
+	"sops": {
+		"lastmodified": %q,
+		"mac": %q,
+		"encrypted_regex": "^Password$"
+	}
+}`,
+		sopsTestEncrypt(t, "secret", "str", "Db:Password:"),
+		sopsTestLastModified,
+		sopsTestMac(t, "localhost", "secret"),
+	)
+
+	config, err := NewSopsJsonSource([]byte(json)).WithDataKey(sopsTestKey).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", config.Get("Db:Host"))
+	assert.Equal(t, "secret", config.Get("Db:Password"))
+}
+
+func Test_SopsJsonSource_AgeIdentityFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell script as fake age command")
+	}
+
+	// Fake "age" command which outputs the data key when given the right identity.
+	dir := t.TempDir()
+	identity := filepath.Join(dir, "keys.txt")
+	assert.NoError(t, os.WriteFile(identity, []byte("AGE-SECRET-KEY-TEST"), 0600))
+	script := strings.Join([]string{
+		"#!/bin/sh",
+		`[ "$3" = "` + identity + `" ] || { echo "no identity matched" >&2; exit 1; }`,
+		"cat > /dev/null",
+		`printf '%s' '` + string(sopsTestKey) + `'`,
+	}, "\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "age"), []byte(script), 0700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	config, err := NewSopsJsonSource(sopsTestFile(t, "plain")).WithAgeIdentityFile(identity).Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "server=db", config.Get("ConnectionStrings:Sql"))
+
+	_, err = NewSopsJsonSource(sopsTestFile(t, "plain")).WithAgeIdentityFile("other").Build()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no identity matched")
+		var ageErr *AgeError
+		if assert.ErrorAs(t, err, &ageErr) {
+			assert.Equal(t, "age1test", ageErr.Recipient)
+		}
+	}
+}
+
+func Test_SopsJsonSource_AgeNotFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := NewSopsJsonSource(sopsTestFile(t, "plain")).WithAgeIdentityFile("keys.txt").Build()
+	var ageErr *AgeError
+	if assert.ErrorAs(t, err, &ageErr) {
+		assert.Equal(t, "", ageErr.Recipient)
+	}
+	assert.ErrorIs(t, err, exec.ErrNotFound)
+}
+
+// sopsFixtureDataKey is the data key of testdata/sops/appsettings.sops.json,
+// which was encrypted by sops 3.9.4 for the age identity in testdata/sops/age-key.txt.
+const sopsFixtureDataKey = "0547fc23af051640feb183bdb6acf71aab13c7e4dc777b33f5c4f839a4c1df0b"
+
+func sopsFixtureAssert(t *testing.T, config Config) {
+	assert.Equal(t, "Server=db;Password=p@ss\"word", config.Get("ConnectionStrings:Sql"))
+	assert.Equal(t, "Warning", config.Get("Logging:LogLevel:Default"))
+	assert.Equal(t, "8080", config.Get("Port"))
+	assert.Equal(t, "0.25", config.Get("Ratio"))
+	assert.Equal(t, "True", config.Get("Enabled"))
+	assert.Equal(t, "a.example.com", config.Get("Hosts:0"))
+	assert.Equal(t, "b.example.com", config.Get("Hosts:1"))
+	assert.Equal(t, "Grüß Gott", config.Get("Greeting"))
+	assert.Equal(t, "not a secret", config.Get("Description_unencrypted"))
+}
+
+func Test_SopsJsonSource_Fixture(t *testing.T) {
+	json, err := os.ReadFile(filepath.Join("testdata", "sops", "appsettings.sops.json"))
+	assert.NoError(t, err)
+	key, err := hex.DecodeString(sopsFixtureDataKey)
+	assert.NoError(t, err)
+
+	config, err := NewSopsJsonSource(json).WithDataKey(key).Build()
+	if assert.NoError(t, err) {
+		sopsFixtureAssert(t, config)
+	}
+
+	// The entries are located in the encrypted file, and keep the kinds of
+	// the decrypted values.
+	entry, _ := getConfigEntry(config, "Ratio")
+	if jsonEntry, ok := entry.(JsonEntry); assert.True(t, ok) {
+		assert.Equal(t, Location{Line: 11, Column: 11}, jsonEntry.Location())
+		assert.Equal(t, JsonValueKindNumber, jsonEntry.JsonKind())
+	}
+	if secret, ok := entry.(SecretEntry); assert.True(t, ok) {
+		assert.True(t, secret.Secret())
+	}
+
+	entry, _ = getConfigEntry(config, "Description_unencrypted")
+	if jsonEntry, ok := entry.(JsonEntry); assert.True(t, ok) {
+		assert.Equal(t, 18, jsonEntry.Location().Line)
+		assert.Equal(t, JsonValueKindString, jsonEntry.JsonKind())
+	}
+	_, ok := entry.(SecretEntry)
+	assert.False(t, ok)
+}
+
+func Test_SopsJsonSource_FixtureAge(t *testing.T) {
+	if _, err := exec.LookPath("age"); err != nil {
+		t.Skip("age is not on the PATH")
+	}
+
+	json, err := os.ReadFile(filepath.Join("testdata", "sops", "appsettings.sops.json"))
+	assert.NoError(t, err)
+
+	config, err := NewSopsJsonSource(json).WithAgeIdentityFile(filepath.Join("testdata", "sops", "age-key.txt")).Build()
+	if assert.NoError(t, err) {
+		sopsFixtureAssert(t, config)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SourceError describes a [config.Source] which could not be built. It wraps
+// the reason, e.g. [config.ParseError], [config.DuplicateKeyError] or
+// fs.ErrNotExist, which can be checked with errors.As and errors.Is.
+type SourceError struct {
+	// Type is the type of the source, e.g. "JsonSource".
+	Type string
+	// Source is the name of the source.
+	Source string
+	// Path is the path of the file of the source, empty if the source is
+	// not a file.
+	Path string
+	// Err is the reason the source could not be built.
+	Err error
+}
+
+// newSourceError creates [config.SourceError].
+func newSourceError(sourceType string, source string, path string, err error) *SourceError {
+	return &SourceError{
+		Type:   sourceType,
+		Source: source,
+		Path:   path,
+		Err:    err,
+	}
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %s: %v", e.Type, e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError describes invalid content of a source, e.g. invalid Json.
+type ParseError struct {
+	// Path is the path of the file, empty if the source is not a file.
+	Path string
+	// Line is 1-based line of the error, zero when not known.
+	Line int
+	// Column is 1-based column of the error in the line, in bytes.
+	Column int
+	// Key is the key where the error is, empty when not known.
+	Key string
+	// Err is the reason the content could not be parsed.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	location := Location{File: e.Path, Line: e.Line, Column: e.Column}.String()
+	switch {
+	case location != "" && e.Key != "":
+		return fmt.Sprintf("%s: key '%s': %v", location, e.Key, e.Err)
+	case location != "":
+		return fmt.Sprintf("%s: %v", location, e.Err)
+	case e.Key != "":
+		return fmt.Sprintf("key '%s': %v", e.Key, e.Err)
+	default:
+		return e.Err.Error()
+	}
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateKeyError describes a key which is defined twice in a source. The
+// keys are compared case-insensitively like in .NET, so "Foo" and "foo" in the
+// same Json object are duplicates.
+type DuplicateKeyError struct {
+	// Key is the duplicate key, as it is spelled the second time.
+	Key string
+	// First is where the key is defined first.
+	First Location
+	// Second is where the key is defined again.
+	Second Location
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate key '%s' at %s, first defined at %s", e.Key, e.Second, e.First)
+}
+
+// newJsonParseError creates [config.ParseError] for the error of encoding/json
+// at the offset of the input, or at the offset of the syntax error.
+func newJsonParseError(input []byte, path string, offset int64, err error) *ParseError {
+	// The offset of syntax error is after the invalid character.
+	if syntaxErr, ok := err.(*json.SyntaxError); ok && syntaxErr.Offset > 0 {
+		offset = syntaxErr.Offset - 1
+	}
+	if offset > int64(len(input)) {
+		offset = int64(len(input))
+	}
+
+	line, column := jsonPosition(input, int(offset))
+	return &ParseError{Path: path, Line: line, Column: column, Err: err}
+}
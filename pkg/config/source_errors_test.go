@@ -0,0 +1,67 @@
+package config
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SourceError_ParseError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"appsettings.json": {Data: []byte("{\n  \"Logging\": {\n    \"Level\": Info\n  }\n}")},
+	}
+
+	_, err := NewJsonFileSource(fsys, "appsettings.json").Build()
+
+	var sourceErr *SourceError
+	if assert.True(t, errors.As(err, &sourceErr)) {
+		assert.Equal(t, "FileSource", sourceErr.Type)
+		assert.Equal(t, "appsettings.json", sourceErr.Source)
+		assert.Equal(t, "appsettings.json", sourceErr.Path)
+	}
+
+	var parseErr *ParseError
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, "appsettings.json", parseErr.Path)
+		assert.Equal(t, 3, parseErr.Line)
+		assert.Equal(t, 14, parseErr.Column)
+		assert.Equal(t, "Logging:Level", parseErr.Key)
+	}
+	assert.Contains(t, err.Error(), "FileSource: appsettings.json: appsettings.json:3:14: key 'Logging:Level': invalid character 'I'")
+
+	_, err = NewJsonSource([]byte(`{"a": 1 /* unterminated`)).Build()
+	if assert.True(t, errors.As(err, &parseErr)) {
+		assert.Equal(t, 1, parseErr.Line)
+		assert.Equal(t, 9, parseErr.Column)
+	}
+}
+
+func Test_SourceError_DuplicateKeyError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"appsettings.json": {Data: []byte("{\n  \"Foo\": {\"Bar\": 1},\n  \"foo\": {\"BAR\": 2}\n}")},
+	}
+
+	builder := NewBuilder()
+	builder.Properties()[PropertyFileSystem] = fsys
+	builder.AddSource(NewFileSource("appsettings.json"))
+	_, err := builder.Build()
+
+	var duplicateErr *DuplicateKeyError
+	if assert.True(t, errors.As(err, &duplicateErr)) {
+		assert.Equal(t, "foo:BAR", duplicateErr.Key)
+		assert.Equal(t, Location{File: "appsettings.json", Line: 2, Column: 18}, duplicateErr.First)
+		assert.Equal(t, Location{File: "appsettings.json", Line: 3, Column: 18}, duplicateErr.Second)
+	}
+	assert.EqualError(t, err, "FileSource: appsettings.json: duplicate key 'foo:BAR' at appsettings.json:3:18, first defined at appsettings.json:2:18")
+}
+
+func Test_SourceError_Is(t *testing.T) {
+	_, err := NewJsonFileSource(fstest.MapFS{}, "missing.json").Build()
+
+	var sourceErr *SourceError
+	assert.True(t, errors.As(err, &sourceErr))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NewSsmParameterStoreFileClient creates [config.SsmParameterStoreClient] which
+// reads parameters from a local Json file, so no AWS access is needed. The file
+// is the output of "aws ssm get-parameters-by-path", i.e. {"Parameters": [...]},
+// or an array of such pages.
+//
+// SecureString values are returned as they are in the file, which is plain
+// text when the file was produced with "--with-decryption".
+func NewSsmParameterStoreFileClient(path string) *SsmParameterStoreFileClient {
+	return &SsmParameterStoreFileClient{path: path}
+}
+
+// SsmParameterStoreFileClient implements [config.SsmParameterStoreClient] interface.
+type SsmParameterStoreFileClient struct {
+	path string
+}
+
+// GetParametersByPath reads the file and returns all parameters under the
+// path in one page. Part of [config.SsmParameterStoreClient] interface.
+func (c *SsmParameterStoreFileClient) GetParametersByPath(input SsmGetParametersByPathInput) (*SsmGetParametersByPathOutput, error) {
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := loadSsmParameterPages(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", c.path)
+	}
+
+	output := &SsmGetParametersByPathOutput{}
+	for _, page := range pages {
+		for _, parameter := range page.Parameters {
+			if matchSsmParameterPath(input.Path, input.Recursive, parameter.Name) {
+				output.Parameters = append(output.Parameters, parameter)
+			}
+		}
+	}
+	return output, nil
+}
+
+func loadSsmParameterPages(b []byte) ([]SsmGetParametersByPathOutput, error) {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		var pages []SsmGetParametersByPathOutput
+		err := json.Unmarshal(b, &pages)
+		return pages, err
+	}
+
+	var page SsmGetParametersByPathOutput
+	err := json.Unmarshal(b, &page)
+	return []SsmGetParametersByPathOutput{page}, err
+}
+
+// matchSsmParameterPath reports whether the parameter is under the path. Without
+// recursion only direct children of the path match, same as in the AWS API.
+func matchSsmParameterPath(path string, recursive bool, name string) bool {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	return recursive || !strings.Contains(name[len(prefix):], "/")
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Types of parameters in AWS Systems Manager Parameter Store.
+const (
+	SsmParameterTypeString       = "String"
+	SsmParameterTypeStringList   = "StringList"
+	SsmParameterTypeSecureString = "SecureString"
+)
+
+// NewSsmParameterStoreSource creates configuration source for AWS Systems Manager
+// Parameter Store in the same way as .NET AddSystemsManager from
+// Amazon.Extensions.Configuration.SystemsManager does.
+//
+// All parameters under the path are read recursively with decryption using the
+// client, which can be backed by a local fixture, see
+// [config.NewSsmParameterStoreFileClient].
+//
+// The mapping rules are the same as in .NET:
+//
+//   - The path is stripped from parameter names and "/" becomes ":", e.g.
+//     "/myapp/prod/Logging/LogLevel" under "/myapp/prod" becomes "Logging:LogLevel".
+//   - StringList values are split by "," into indexed children, e.g. "a,b"
+//     under "Hosts" becomes "Hosts:0" and "Hosts:1".
+//   - Parameters which map to the same key are an error.
+//
+// Entries with SecureString values implement [config.SecretEntry].
+func NewSsmParameterStoreSource(client SsmParameterStoreClient, path string) *SsmParameterStoreSource {
+	return &SsmParameterStoreSource{
+		name:   "SsmParameterStoreSource",
+		client: client,
+		path:   path,
+	}
+}
+
+// SsmParameterStoreSource implements [config.Source] interface.
+type SsmParameterStoreSource struct {
+	name   string
+	client SsmParameterStoreClient
+	path   string
+}
+
+// WithName sets the name of this source and returns itself.
+func (s *SsmParameterStoreSource) WithName(name string) *SsmParameterStoreSource {
+	s.name = name
+	return s
+}
+
+// Name is the name of this source. Part of [config.Source] interface.
+func (s *SsmParameterStoreSource) Name() string {
+	return s.name
+}
+
+// Build builds Config. Part of [config.Source] interface.
+func (s *SsmParameterStoreSource) Build() (Config, error) {
+	parameters, err := s.getParameters()
+	if err != nil {
+		return nil, newSourceError("SsmParameterStoreSource", s.name, "", err)
+	}
+
+	m := make(map[string]string)
+	names := make(map[string]string)
+	secretKeys := make(map[string]bool)
+	add := func(parameter SsmParameter, key string, value string) error {
+		normalized := normalizeKey(key)
+		if name, found := names[normalized]; found {
+			return newSourceError("SsmParameterStoreSource", s.name, "",
+				errors.Errorf("parameters '%s' and '%s' map to the same key '%s'", name, parameter.Name, normalized))
+		}
+		names[normalized] = parameter.Name
+		m[key] = value
+		if parameter.Type == SsmParameterTypeSecureString {
+			secretKeys[normalized] = true
+		}
+		return nil
+	}
+
+	for _, parameter := range parameters {
+		key := s.parameterKey(parameter.Name)
+
+		if parameter.Type != SsmParameterTypeStringList {
+			if err := add(parameter, key, parameter.Value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for i, value := range strings.Split(parameter.Value, ",") {
+			if err := add(parameter, fmt.Sprintf("%s%s%d", key, keyDelimiter, i), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return newSecretConfig(newConfigImpl(s, m), secretKeys), nil
+}
+
+func (s *SsmParameterStoreSource) getParameters() ([]SsmParameter, error) {
+	var parameters []SsmParameter
+	input := SsmGetParametersByPathInput{
+		Path:           s.path,
+		Recursive:      true,
+		WithDecryption: true,
+	}
+
+	for {
+		output, err := s.client.GetParametersByPath(input)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, output.Parameters...)
+
+		if output.NextToken == "" {
+			return parameters, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// parameterKey strips the path from the parameter name and converts it to
+// the configuration key. Parameter names are case-sensitive, like the paths
+// in [config.SsmParameterStoreClient].
+func (s *SsmParameterStoreSource) parameterKey(name string) string {
+	if prefix := strings.TrimSuffix(s.path, "/") + "/"; strings.HasPrefix(name, prefix) {
+		name = name[len(prefix):]
+	}
+	return strings.ReplaceAll(strings.TrimLeft(name, "/"), "/", keyDelimiter)
+}
+
+// SsmParameterStoreClient reads parameters from AWS Systems Manager Parameter Store.
+type SsmParameterStoreClient interface {
+	// GetParametersByPath returns a page of parameters under the path, in the
+	// same way as the "GetParametersByPath" operation of the AWS API.
+	GetParametersByPath(input SsmGetParametersByPathInput) (*SsmGetParametersByPathOutput, error)
+}
+
+// SsmGetParametersByPathInput is the request of the "GetParametersByPath" operation.
+type SsmGetParametersByPathInput struct {
+	Path           string
+	Recursive      bool
+	WithDecryption bool
+	NextToken      string
+}
+
+// SsmGetParametersByPathOutput is the response of the "GetParametersByPath"
+// operation, in the same format as "aws ssm get-parameters-by-path" output.
+type SsmGetParametersByPathOutput struct {
+	Parameters []SsmParameter `json:"Parameters"`
+	NextToken  string         `json:"NextToken,omitempty"`
+}
+
+// SsmParameter is a parameter in Parameter Store.
+type SsmParameter struct {
+	Name     string `json:"Name"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	Version  int64  `json:"Version,omitempty"`
+	ARN      string `json:"ARN,omitempty"`
+	DataType string `json:"DataType,omitempty"`
+}
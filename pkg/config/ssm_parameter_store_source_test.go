@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ssmParameterStoreTestJson = `[
+	{
+		"Parameters": [
+			{"Name": "/myapp/prod/Logging/LogLevel", "Type": "String", "Value": "Debug", "Version": 3, "DataType": "text"},
+			{"Name": "/myapp/prod/Hosts", "Type": "StringList", "Value": "a.example.com,b.example.com"}
+		],
+		"NextToken": "ignored"
+	},
+	{
+		"Parameters": [
+			{"Name": "/myapp/prod/ConnectionStrings/Sql", "Type": "SecureString", "Value": "Server=db;Password=secret"},
+			{"Name": "/myapp/dev/Logging/LogLevel", "Type": "String", "Value": "Trace"}
+		]
+	}
+]`
+
+func newSsmParameterStoreTestClient(t *testing.T) *SsmParameterStoreFileClient {
+	path := filepath.Join(t.TempDir(), "parameters.json")
+	assert.NoError(t, os.WriteFile(path, []byte(ssmParameterStoreTestJson), 0600))
+	return NewSsmParameterStoreFileClient(path)
+}
+
+func Test_SsmParameterStoreSource(t *testing.T) {
+	source := NewSsmParameterStoreSource(newSsmParameterStoreTestClient(t), "/myapp/prod")
+	config, err := source.Build()
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"ConnectionStrings:Sql", "Hosts:0", "Hosts:1", "Logging:LogLevel"}, config.Keys())
+	assert.Equal(t, "Debug", config.Get("Logging:LogLevel"))
+	assert.Equal(t, "a.example.com", config.Get("Hosts:0"))
+	assert.Equal(t, "b.example.com", config.Get("Hosts:1"))
+
+	builder := NewBuilder()
+	builder.AddSource(source)
+	root, err := builder.Build()
+	assert.NoError(t, err)
+
+	entry := root.GetEntry("ConnectionStrings:Sql")
+	assert.Equal(t, "Server=db;Password=secret", entry.Value())
+	if secret, ok := entry.(SecretEntry); assert.True(t, ok) {
+		assert.True(t, secret.Secret())
+	}
+
+	_, ok := root.GetEntry("Logging:LogLevel").(SecretEntry)
+	assert.False(t, ok)
+}
+
+// ssmParameterStorePagedClient returns one parameter per page.
+type ssmParameterStorePagedClient struct {
+	parameters []SsmParameter
+	inputs     []SsmGetParametersByPathInput
+}
+
+func (c *ssmParameterStorePagedClient) GetParametersByPath(input SsmGetParametersByPathInput) (*SsmGetParametersByPathOutput, error) {
+	c.inputs = append(c.inputs, input)
+	i := len(c.inputs) - 1
+	output := &SsmGetParametersByPathOutput{Parameters: c.parameters[i : i+1]}
+	if i+1 < len(c.parameters) {
+		output.NextToken = "next"
+	}
+	return output, nil
+}
+
+func Test_SsmParameterStoreSource_Paging(t *testing.T) {
+	client := &ssmParameterStorePagedClient{parameters: []SsmParameter{
+		{Name: "/myapp/a", Type: SsmParameterTypeString, Value: "1"},
+		{Name: "/myapp/b/c", Type: SsmParameterTypeString, Value: "2"},
+	}}
+
+	config, err := NewSsmParameterStoreSource(client, "/myapp/").Build()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b:c"}, config.Keys())
+
+	if assert.Len(t, client.inputs, 2) {
+		assert.Equal(t, SsmGetParametersByPathInput{Path: "/myapp/", Recursive: true, WithDecryption: true}, client.inputs[0])
+		assert.Equal(t, "next", client.inputs[1].NextToken)
+	}
+}
+
+func Test_SsmParameterStoreSource_PathCase(t *testing.T) {
+	source := NewSsmParameterStoreSource(&ssmParameterStorePagedClient{}, "/myapp")
+	assert.Equal(t, "a:b", source.parameterKey("/myapp/a/b"))
+	assert.Equal(t, "MyApp:a", source.parameterKey("/MyApp/a"))
+	assert.Equal(t, "myappx:a", source.parameterKey("/myappx/a"))
+}
+
+func Test_SsmParameterStoreSource_DuplicateKeys(t *testing.T) {
+	client := &ssmParameterStorePagedClient{parameters: []SsmParameter{
+		{Name: "/myapp/Hosts:0", Type: SsmParameterTypeString, Value: "1"},
+		{Name: "/myapp/hosts", Type: SsmParameterTypeStringList, Value: "a,b"},
+	}}
+
+	_, err := NewSsmParameterStoreSource(client, "/myapp").Build()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "/myapp/Hosts:0")
+	}
+}
+
+func Test_SsmParameterStoreFileClient_NotRecursive(t *testing.T) {
+	output, err := newSsmParameterStoreTestClient(t).GetParametersByPath(SsmGetParametersByPathInput{Path: "/myapp/prod"})
+	assert.NoError(t, err)
+	if assert.Len(t, output.Parameters, 1) {
+		assert.Equal(t, "/myapp/prod/Hosts", output.Parameters[0].Name)
+	}
+
+	_, err = NewSsmParameterStoreFileClient("missing.json").GetParametersByPath(SsmGetParametersByPathInput{Path: "/"})
+	assert.Error(t, err)
+}